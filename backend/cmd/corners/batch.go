@@ -0,0 +1,68 @@
+// batch command
+
+package main
+
+import (
+	"Backend/src/batch"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+)
+
+func runBatch(args []string) error {
+	fs := newFlagSet("batch", "[flags] file|directory|glob...")
+	d := addDetectFlags(fs)
+	defaults := batch.DefaultOptions()
+	output := fs.String("o", defaults.Output, "output directory, mirroring the input directories")
+	format := fs.String("format", string(defaults.Format), "result format: json, csv, ndjson or yaml")
+	withOverlay := fs.Bool("overlay", false, "also render the corners over every image as png")
+	workers := fs.Int("workers", 0, "images processed at once, 0 for one per cpu")
+	resume := fs.Bool("resume", defaults.Resume, "skip images an earlier run with the same settings finished")
+	quiet := fs.Bool("quiet", false, "do not report every image on stderr")
+	if err := parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+	cfg, err := d.config()
+	if err != nil {
+		return err
+	}
+	f, err := outputFormat(*format, "")
+	if err != nil {
+		return err
+	}
+	inputs, err := batch.Collect(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	opts := batch.Options{Output: *output, Config: cfg, Format: f, Overlay: *withOverlay, Workers: *workers, Resume: *resume}
+	finished := 0
+	opts.Process = func(e batch.Entry) {
+		finished++
+		if *quiet {
+			return
+		}
+		if e.Status == batch.StatusOK {
+			fmt.Fprintf(os.Stderr, "[%d] ok %s: %d corners in %.0f ms\n", finished, e.Input, e.Corners, e.Millis)
+		} else {
+			fmt.Fprintf(os.Stderr, "[%d] failed %s: %s\n", finished, e.Input, e.Error)
+		}
+	}
+	// an interrupt stops handing out images but still writes the manifest, so the run can resume
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	m, err := batch.Run(ctx, inputs, opts)
+	if m != nil {
+		fmt.Fprintf(os.Stderr, "%d images: %d ok, %d failed, %d skipped, manifest in %s\n",
+			m.Total, m.Succeeded, m.Failed, m.Skipped, filepath.Join(*output, batch.ManifestName))
+	}
+	if err != nil {
+		return err
+	}
+	if m.Failed > 0 {
+		return fmt.Errorf("%d of %d images failed", m.Failed, m.Total)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// chessboard, calibrate and undistort commands
+
+package main
+
+import (
+	"Backend/src/batch"
+	"Backend/src/calibration"
+	"Backend/src/chessboard"
+	"Backend/src/corner"
+	"Backend/src/imaging"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// addBoardFlags registers the board size and the chessboard detector options on fs
+func addBoardFlags(fs *flag.FlagSet) (cols, rows *int, opts *chessboard.Options) {
+	cols = fs.Int("cols", 9, "inner corners along a row of the board")
+	rows = fs.Int("rows", 6, "inner corners along a column of the board")
+	o := chessboard.DefaultOptions()
+	fs.Float64Var(&o.Sigma, "sigma", o.Sigma, "smoothing scale of the hessian used for saddle candidates")
+	fs.Float64Var(&o.Threshold, "threshold", o.Threshold, "fraction of the strongest saddle response a candidate must reach")
+	fs.IntVar(&o.MaxCandidates, "max-candidates", o.MaxCandidates, "strongest candidates considered for grid fitting")
+	fs.IntVar(&o.RefineRadius, "refine-radius", o.RefineRadius, "half size of the sub-pixel refinement window, 0 disables refinement")
+	return cols, rows, &o
+}
+
+func runChessboard(args []string) error {
+	fs := newFlagSet("chessboard", "[flags] image")
+	cols, rows, opts := addBoardFlags(fs)
+	out := fs.String("o", "-", "board json output, - for stdout")
+	marked := fs.String("image", "", "also save the image with the board corners marked, png, jpeg or tiff")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	img, err := loadImage(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	board, err := chessboard.Detect(imaging.ToFloat(img), *cols, *rows, *opts)
+	if err != nil {
+		return err
+	}
+	if *marked != "" {
+		if err := imaging.Save(corner.Draw(img, board.Corners), *marked); err != nil {
+			return err
+		}
+	}
+	return writeJSON(*out, board)
+}
+
+func runCalibrate(args []string) error {
+	fs := newFlagSet("calibrate", "[flags] file|directory|glob...")
+	cols, rows, board := addBoardFlags(fs)
+	square := fs.Float64("square", 1, "side of a board square, in the unit of the translations")
+	opts := calibration.DefaultOptions()
+	fs.IntVar(&opts.RadialTerms, "radial", opts.RadialTerms, "number of radial coefficients estimated, 0 to 3")
+	fs.BoolVar(&opts.Tangential, "tangential", opts.Tangential, "estimate the tangential coefficients p1 and p2")
+	fs.IntVar(&opts.Iterations, "iterations", opts.Iterations, "levenberg-marquardt iteration limit")
+	out := fs.String("o", "-", "calibration output, yaml for a .yml or .yaml extension and json otherwise, - for json on stdout")
+	if err := parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+	inputs, err := batch.Collect(fs.Args())
+	if err != nil {
+		return err
+	}
+	paths := make([]string, len(inputs))
+	for i, in := range inputs {
+		paths[i] = in.Path
+	}
+
+	result, skipped, err := calibration.CalibrateImages(paths, *cols, *rows, *square, *board, opts)
+	names := make([]string, 0, len(skipped))
+	for name := range skipped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "skipped %s: %s\n", name, skipped[name])
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d views, rms reprojection error %.3f px\n", len(result.Views), result.RMS)
+	if *out == "" || *out == "-" {
+		return result.WriteJSON(os.Stdout)
+	}
+	return result.Save(*out)
+}
+
+func runUndistort(args []string) error {
+	fs := newFlagSet("undistort", "[flags] calibration image")
+	out := fs.String("o", "undistorted.png", "output image, png, jpeg or tiff")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	result, err := calibration.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	img, err := loadImage(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	return imaging.Save(calibration.Undistort(img, result.Camera), *out)
+}
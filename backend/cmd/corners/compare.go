@@ -0,0 +1,194 @@
+// compare and bench commands
+
+package main
+
+import (
+	"Backend/src/annotation"
+	"Backend/src/batch"
+	"Backend/src/corner"
+	"Backend/src/detector"
+	"Backend/src/serialize"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadPoints returns the corners of an image, detected with the flags, or those of a result or a
+// coco, labelme or cvat annotation file. For annotations of several images the one of imageName
+// is used.
+func loadPoints(path, format, imageName string, d *detectFlags) ([]corner.Corner, error) {
+	if batch.IsImage(path) {
+		result, err := detectImage(path, d, 0)
+		if err != nil {
+			return nil, err
+		}
+		return result.Corners, nil
+	}
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	images, _, err := annotation.Read(bytes.NewReader(data))
+	if err == nil {
+		img, ok := annotation.Find(images, imageName)
+		if !ok {
+			return nil, fmt.Errorf("%s has no annotations for %q", path, imageName)
+		}
+		return img.Points, nil
+	}
+	if !errors.Is(err, annotation.ErrFormat) {
+		return nil, err
+	}
+	f, err := outputFormat(format, path)
+	if err != nil {
+		return nil, err
+	}
+	result, err := serialize.Read(bytes.NewReader(data), f)
+	if err != nil {
+		return nil, err
+	}
+	return result.Corners, nil
+}
+
+func runCompare(args []string) error {
+	fs := newFlagSet("compare", "[flags] detected truth")
+	d := addDetectFlags(fs)
+	tolerance := fs.Float64("tolerance", 3, "largest distance in pixels at which a detection matches a truth point")
+	imageName := fs.String("image", "", "image to take from annotations of several images, default the detected file")
+	format := fs.String("corners-format", "", "format of result files that are not json, default from their extension")
+	out := fs.String("o", "-", "output json file, - for stdout")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	detectedPath, truthPath := fs.Arg(0), fs.Arg(1)
+	if detectedPath == "-" && truthPath == "-" {
+		return errors.New("detected and truth cannot both come from stdin")
+	}
+	name := *imageName
+	if name == "" {
+		name = detectedPath
+	}
+	detected, err := loadPoints(detectedPath, *format, name, d)
+	if err != nil {
+		return err
+	}
+	truth, err := loadPoints(truthPath, *format, name, d)
+	if err != nil {
+		return err
+	}
+	return writeJSON(*out, struct {
+		Detected   string                `json:"detected"`
+		Truth      string                `json:"truth"`
+		Evaluation annotation.Evaluation `json:"evaluation"`
+	}{detectedPath, truthPath, annotation.Evaluate(detected, truth, *tolerance)})
+}
+
+// timing is one benchmark row
+type timing struct {
+	Image    string  `json:"image"`
+	Detector string  `json:"detector"`
+	Width    int     `json:"width"`
+	Height   int     `json:"height"`
+	Corners  int     `json:"corners"`
+	Runs     int     `json:"runs"`
+	MinMS    float64 `json:"min_ms"`
+	MedianMS float64 `json:"median_ms"`
+	MeanMS   float64 `json:"mean_ms"`
+}
+
+func runBench(args []string) error {
+	fs := newFlagSet("bench", "[flags] image...")
+	d := addDetectFlags(fs)
+	names := fs.String("detectors", "", "comma separated detectors to time, all for every one, default the -detector flag")
+	runs := fs.Int("runs", 5, "timed runs per detector and image, after one warm up run")
+	format := fs.String("format", "json", "json or csv")
+	out := fs.String("o", "-", "output file, - for stdout")
+	if err := parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("format must be json or csv, not %q", *format)
+	}
+	if *runs < 1 {
+		return errors.New("runs must be at least 1")
+	}
+	cfg, err := d.config()
+	if err != nil {
+		return err
+	}
+	detectors := []string{cfg.Detector}
+	switch *names {
+	case "":
+	case "all":
+		detectors = detector.Names
+	default:
+		detectors = strings.Split(*names, ",")
+	}
+
+	var rows []timing
+	for _, path := range fs.Args() {
+		img, err := loadImage(path)
+		if err != nil {
+			return err
+		}
+		for _, name := range detectors {
+			c := cfg
+			c.Detector = strings.TrimSpace(name)
+			result, err := detector.Detect(img, c)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			durations := make([]float64, *runs)
+			for i := range durations {
+				start := time.Now()
+				detector.Detect(img, c)
+				durations[i] = float64(time.Since(start).Microseconds()) / 1000
+			}
+			sort.Float64s(durations)
+			var sum float64
+			for _, v := range durations {
+				sum += v
+			}
+			rows = append(rows, timing{
+				Image: path, Detector: c.Detector, Width: result.Width, Height: result.Height,
+				Corners: len(result.Corners), Runs: *runs,
+				MinMS: durations[0], MedianMS: durations[len(durations)/2], MeanMS: sum / float64(len(durations)),
+			})
+		}
+	}
+
+	if *format == "json" {
+		return writeJSON(*out, rows)
+	}
+	w, err := create(*out)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"image", "detector", "width", "height", "corners", "runs", "min_ms", "median_ms", "mean_ms"})
+	for _, r := range rows {
+		cw.Write([]string{
+			r.Image, r.Detector, strconv.Itoa(r.Width), strconv.Itoa(r.Height), strconv.Itoa(r.Corners), strconv.Itoa(r.Runs),
+			strconv.FormatFloat(r.MinMS, 'f', 3, 64), strconv.FormatFloat(r.MedianMS, 'f', 3, 64), strconv.FormatFloat(r.MeanMS, 'f', 3, 64),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
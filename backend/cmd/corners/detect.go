@@ -0,0 +1,228 @@
+// detect and render commands
+
+package main
+
+import (
+	"Backend/src/detector"
+	"Backend/src/imaging"
+	"Backend/src/metadata"
+	"Backend/src/overlay"
+	"Backend/src/serialize"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// outputFormat returns the result format given by name, or else by the extension of path, or json
+func outputFormat(name, path string) (serialize.Format, error) {
+	if name != "" {
+		return serialize.ParseFormat(name)
+	}
+	if format, err := serialize.FormatFromPath(path); err == nil {
+		return format, nil
+	}
+	return serialize.JSON, nil
+}
+
+// detectImage runs the configured detector, choosing its threshold for about target corners when
+// target is positive
+func detectImage(path string, d *detectFlags, target int) (*detector.Result, error) {
+	cfg, err := d.config()
+	if err != nil {
+		return nil, err
+	}
+	img, err := loadImage(path)
+	if err != nil {
+		return nil, err
+	}
+	if target > 0 {
+		opts := detector.DefaultAutoOptions()
+		opts.Target = target
+		result, _, err := detector.AutoThreshold(img, cfg, opts)
+		return result, err
+	}
+	return detector.Detect(img, cfg)
+}
+
+func runDetect(args []string) error {
+	fs := newFlagSet("detect", "[flags] image")
+	d := addDetectFlags(fs)
+	out := fs.String("o", "-", "output file, - for stdout")
+	format := fs.String("format", "", "json, csv, ndjson or yaml, default from the output extension or json")
+	target := fs.Int("target", 0, "pick the threshold automatically to return about this many corners")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	f, err := outputFormat(*format, *out)
+	if err != nil {
+		return err
+	}
+	result, err := detectImage(fs.Arg(0), d, *target)
+	if err != nil {
+		return err
+	}
+	w, err := create(*out)
+	if err != nil {
+		return err
+	}
+	if err := serialize.Write(w, result, f); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// styleFlags are the overlay marker flags, named like the query parameters of the http routes
+type styleFlags struct {
+	marker, sizeBy, color, colormap *string
+	radius, minRadius, lineWidth    *float64
+	ticks, labels, antialias        *bool
+}
+
+func addStyleFlags(fs *flag.FlagSet) *styleFlags {
+	defaults := overlay.DefaultOptions()
+	names := make([]string, len(overlay.Markers))
+	for i, m := range overlay.Markers {
+		names[i] = string(m)
+	}
+	return &styleFlags{
+		marker:    fs.String("marker", string(defaults.Marker), "marker shape: "+strings.Join(names, ", ")),
+		sizeBy:    fs.String("size-by", string(defaults.SizeBy), "marker size: fixed, score or scale"),
+		color:     fs.String("marker-color", "#ff0000", "marker color as #rrggbb or #rrggbbaa"),
+		colormap:  fs.String("colormap", "", "color markers by score rank with viridis, jet or magma"),
+		radius:    fs.Float64("radius", defaults.Radius, "marker radius in pixels"),
+		minRadius: fs.Float64("min-radius", defaults.MinRadius, "smallest marker radius when sized by score"),
+		lineWidth: fs.Float64("line-width", defaults.LineWidth, "stroke width in pixels"),
+		ticks:     fs.Bool("ticks", false, "draw the orientation of corners that have one"),
+		labels:    fs.Bool("labels", false, "number the corners"),
+		antialias: fs.Bool("antialias", true, "anti-alias the markers"),
+	}
+}
+
+func (s *styleFlags) options() (overlay.Options, error) {
+	opts := overlay.DefaultOptions()
+	var err error
+	if opts.Marker, err = overlay.ParseMarker(*s.marker); err != nil {
+		return opts, err
+	}
+	if opts.SizeBy, err = overlay.ParseSizeBy(*s.sizeBy); err != nil {
+		return opts, err
+	}
+	if opts.Color, err = overlay.ParseColor(*s.color); err != nil {
+		return opts, err
+	}
+	if *s.colormap != "" {
+		if opts.Colormap, err = imaging.ParseColormap(*s.colormap); err != nil {
+			return opts, err
+		}
+	}
+	opts.Radius, opts.MinRadius, opts.LineWidth = *s.radius, *s.minRadius, *s.lineWidth
+	opts.Ticks, opts.Labels, opts.Antialias = *s.ticks, *s.labels, *s.antialias
+	return opts, nil
+}
+
+// loadResult reads a result file in the given format, or the one its extension names, or stdin
+func loadResult(path, format string) (*detector.Result, error) {
+	f, err := outputFormat(format, path)
+	if err != nil {
+		return nil, err
+	}
+	if path == "-" {
+		return serialize.Read(os.Stdin, f)
+	}
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return serialize.Read(r, f)
+}
+
+func runRender(args []string) error {
+	fs := newFlagSet("render", "[flags] image")
+	d := addDetectFlags(fs)
+	style := addStyleFlags(fs)
+	cornersPath := fs.String("corners", "", "result file to draw instead of detecting, - for stdin")
+	cornersFormat := fs.String("corners-format", "", "format of the result file, default from its extension or json")
+	out := fs.String("o", "overlay.png", "output png, jpeg, tiff or svg file, - for png on stdout")
+	embed := fs.Bool("embed", false, "embed the result in png and jpeg output")
+	svgImage := fs.String("svg-image", "embed", "background of svg output: embed, reference or none")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if fs.Arg(0) == "-" && *cornersPath == "-" {
+		return errors.New("the image and the corners cannot both come from stdin")
+	}
+	opts, err := style.options()
+	if err != nil {
+		return err
+	}
+	img, err := loadImage(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var result *detector.Result
+	if *cornersPath != "" {
+		result, err = loadResult(*cornersPath, *cornersFormat)
+	} else {
+		var cfg detector.Config
+		if cfg, err = d.config(); err == nil {
+			result, err = detector.Detect(img, cfg)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	ext := strings.ToLower(filepath.Ext(*out))
+	if *out == "-" {
+		ext = ".png"
+	}
+	var buf bytes.Buffer
+	switch ext {
+	case ".svg":
+		svg := overlay.SVGOptions{Detector: result.Detector}
+		switch *svgImage {
+		case "embed":
+			svg.Image = img
+		case "reference":
+			svg.ImageHref = fs.Arg(0)
+		case "none":
+		default:
+			return fmt.Errorf("svg-image must be embed, reference or none, not %q", *svgImage)
+		}
+		err = overlay.WriteSVG(&buf, result.Corners, img.Bounds().Dx(), img.Bounds().Dy(), opts, svg)
+	case ".png":
+		err = png.Encode(&buf, overlay.Render(img, result.Corners, opts))
+	case ".jpg", ".jpeg":
+		err = jpeg.Encode(&buf, overlay.Render(img, result.Corners, opts), nil)
+	case ".tif", ".tiff":
+		err = imaging.EncodeTIFF(&buf, overlay.Render(img, result.Corners, opts))
+	default:
+		return fmt.Errorf("unsupported output format %q", ext)
+	}
+	if err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if *embed {
+		if data, err = metadata.Embed(data, result); err != nil {
+			return err
+		}
+	}
+	w, err := create(*out)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
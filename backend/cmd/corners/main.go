@@ -0,0 +1,233 @@
+// corners command line tool: detection, rendering, evaluation, matching, tracking, chessboards and
+// calibration, documents, lines, contours, benchmarks, parameter sweeps and the http server behind
+// one binary
+
+package main
+
+import (
+	"Backend/src/detector"
+	"Backend/src/imaging"
+	"Backend/src/preprocess"
+	"Backend/src/server"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const usage = `usage: corners <command> [flags] [arguments]
+
+commands:
+  detect      detect corners in an image and write the result
+  render      draw detected or saved corners over an image as png, jpeg, tiff or svg
+  compare     score detected corners against ground truth
+  match       match corners between two images
+  track       follow corners through a sequence of frames
+  chessboard  find the inner corners of a chessboard
+  calibrate   calibrate a camera from chessboard images
+  undistort   remove lens distortion from an image with a saved calibration
+  document    find a page outline and rectify the page
+  edges       intersect the hough lines through canny edges
+  contours    find contours and their curvature scale space corners
+  bench       time detectors on images
+  sweep       score a detector over a grid or random search of its params on a dataset
+  batch       detect corners in many images with a worker pool, resuming interrupted runs
+  watch       detect corners in images as they arrive in a folder
+  pipeline    run a yaml or json pipeline spec on images
+  serve       run the http server
+
+An image or result argument of "-" reads stdin and an output of "-" writes stdout. Results are
+written as json unless a format is given. Run "corners <command> -h" for the flags of a command.
+`
+
+var commands = map[string]func(args []string) error{
+	"detect":     runDetect,
+	"render":     runRender,
+	"compare":    runCompare,
+	"match":      runMatch,
+	"track":      runTrack,
+	"chessboard": runChessboard,
+	"calibrate":  runCalibrate,
+	"undistort":  runUndistort,
+	"document":   runDocument,
+	"edges":      runEdges,
+	"contours":   runContours,
+	"bench":      runBench,
+	"sweep":      runSweep,
+	"batch":      runBatch,
+	"watch":      runWatch,
+	"pipeline":   runPipeline,
+	"serve":      runServe,
+}
+
+// errUsage reports bad arguments, after the flag set printed its usage
+var errUsage = errors.New("usage")
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "corners: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err := run(os.Args[2:]); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "corners %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// newFlagSet returns a flag set that prints the command synopsis before its flags
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: corners %s %s\n\nflags:\n", name, synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args and checks the number of positional arguments, min to max or more for max -1
+func parse(fs *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < minArgs || (maxArgs >= 0 && fs.NArg() > maxArgs) {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// paramFlags maps the flag of every detector option to its detector.Params name
+var paramFlags = map[string]string{
+	"threshold":   "threshold",
+	"k":           "k",
+	"window":      "window",
+	"min-dist":    "min_dist",
+	"sigma0":      "sigma0",
+	"step":        "step",
+	"scales":      "scales",
+	"diff-ratio":  "diff_ratio",
+	"max-corners": "max_corners",
+}
+
+var paramUsage = map[string]string{
+	"threshold":   "detector threshold, intensity difference for fast and response for the others",
+	"k":           "harris sensitivity constant (harris, harris-laplace)",
+	"window":      "structure tensor window side (harris, shi-tomashi)",
+	"min-dist":    "minimum distance between corners (harris, shi-tomashi)",
+	"sigma0":      "finest integration scale (laplace variants)",
+	"step":        "scale ratio between levels (laplace variants)",
+	"scales":      "number of scale levels (laplace variants)",
+	"diff-ratio":  "differentiation to integration scale ratio (laplace variants)",
+	"max-corners": "most corners kept, 0 for all (laplace variants)",
+}
+
+// detectFlags are the flags selecting a detector config, with an option flag for every
+// detector parameter. Only the option flags given on the command line override the defaults.
+type detectFlags struct {
+	fs         *flag.FlagSet
+	detector   *string
+	preprocess *string
+	color      *string
+	params     map[string]*float64
+}
+
+func addDetectFlags(fs *flag.FlagSet) *detectFlags {
+	d := &detectFlags{
+		fs:         fs,
+		detector:   fs.String("detector", "harris", "detector: "+strings.Join(detector.Names, ", ")),
+		preprocess: fs.String("preprocess", "", `preprocessing pipeline such as "median:3,gamma:0.8", default per detector`),
+		color:      fs.String("color", "gray", "color space for harris and shi-tomashi: gray, rgb or lab"),
+		params:     map[string]*float64{},
+	}
+	names := make([]string, 0, len(paramFlags))
+	for name := range paramFlags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d.params[name] = fs.Float64(name, 0, paramUsage[name])
+	}
+	return d
+}
+
+// config returns the detector config named by the parsed flags
+func (d *detectFlags) config() (detector.Config, error) {
+	cfg := detector.Config{Detector: *d.detector, Params: detector.Params{}}
+	d.fs.Visit(func(f *flag.Flag) {
+		if param, ok := paramFlags[f.Name]; ok {
+			cfg.Params[param] = *d.params[f.Name]
+		}
+	})
+	var err error
+	if *d.preprocess != "" {
+		if cfg.Preprocess, err = preprocess.Parse(*d.preprocess); err != nil {
+			return cfg, err
+		}
+	}
+	cfg.Color, err = imaging.ParseColorSpace(*d.color)
+	return cfg, err
+}
+
+// loadImage decodes the image at path, or from stdin for "-"
+func loadImage(path string) (image.Image, error) {
+	if path == "-" {
+		img, _, err := image.Decode(os.Stdin)
+		return img, err
+	}
+	return imaging.Load(path)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// create opens path for writing, or stdout for "-" and ""
+func create(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+// writeJSON writes v as indented json to path, or stdout for "-" and ""
+func writeJSON(path string, v any) error {
+	w, err := create(path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "[flags]")
+	addr := fs.String("addr", "", "listen address, default :8080 or the PORT environment variable")
+	uploads := fs.String("uploads", "./uploads", "directory uploaded images are stored in")
+	output := fs.String("output", "./output", "directory results are written to and served from")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	r := server.New(*uploads, *output)
+	if *addr == "" {
+		return r.Run()
+	}
+	return r.Run(*addr)
+}
@@ -0,0 +1,198 @@
+// match and track commands
+
+package main
+
+import (
+	"Backend/src/descriptor"
+	"Backend/src/detector"
+	"Backend/src/imaging"
+	"Backend/src/matching"
+	"Backend/src/tracking"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+)
+
+// describe detects the corners of an image and extracts their oriented patches
+func describe(path string, cfg detector.Config, opts descriptor.Options) (image.Image, []descriptor.Patch, error) {
+	img, err := loadImage(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := detector.Detect(img, cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return img, descriptor.Describe(imaging.ToFloat(img), result.Corners, opts), nil
+}
+
+type point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func runMatch(args []string) error {
+	fs := newFlagSet("match", "[flags] image-a image-b")
+	d := addDetectFlags(fs)
+	defaults := matching.DefaultOptions()
+	ratio := fs.Float64("ratio", defaults.Ratio, "lowe's ratio test threshold, 1 to disable")
+	crossCheck := fs.Bool("cross-check", defaults.CrossCheck, "keep only mutual best matches")
+	maxDistance := fs.Float64("max-distance", 0, "largest patch distance kept, 0 for any")
+	patchSize := fs.Int("patch-size", descriptor.DefaultOptions().Size, "descriptor patch side in samples")
+	orientation := fs.String("orientation", "centroid", "patch orientation: centroid or histogram")
+	drawPath := fs.String("draw", "", "also write both images side by side with the matches joined")
+	out := fs.String("o", "-", "output json file, - for stdout")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	if fs.Arg(0) == "-" && fs.Arg(1) == "-" {
+		return errors.New("both images cannot come from stdin")
+	}
+	cfg, err := d.config()
+	if err != nil {
+		return err
+	}
+	opts := descriptor.DefaultOptions()
+	opts.Size = *patchSize
+	switch *orientation {
+	case "centroid":
+		opts.Method = descriptor.Centroid
+	case "histogram":
+		opts.Method = descriptor.Histogram
+	default:
+		return fmt.Errorf("orientation must be centroid or histogram, not %q", *orientation)
+	}
+	imgA, a, err := describe(fs.Arg(0), cfg, opts)
+	if err != nil {
+		return err
+	}
+	imgB, b, err := describe(fs.Arg(1), cfg, opts)
+	if err != nil {
+		return err
+	}
+	matches := matching.BruteForce(a, b, matching.Options{Ratio: *ratio, CrossCheck: *crossCheck, MaxDistance: *maxDistance})
+
+	if *drawPath != "" {
+		if err := imaging.Save(matching.Draw(imgA, imgB, a, b, matches), *drawPath); err != nil {
+			return err
+		}
+	}
+	type pair struct {
+		matching.Match
+		From point `json:"from"`
+		To   point `json:"to"`
+	}
+	pairs := make([]pair, len(matches))
+	for i, m := range matches {
+		ca, cb := a[m.A].Corner, b[m.B].Corner
+		pairs[i] = pair{Match: m, From: point{ca.X, ca.Y}, To: point{cb.X, cb.Y}}
+	}
+	return writeJSON(*out, struct {
+		A       string `json:"a"`
+		B       string `json:"b"`
+		CornerA int    `json:"corners_a"`
+		CornerB int    `json:"corners_b"`
+		Matches []pair `json:"matches"`
+	}{fs.Arg(0), fs.Arg(1), len(a), len(b), pairs})
+}
+
+// frame lists the corners still followed in one frame of a track run, identified by their index in
+// the first frame
+type frame struct {
+	Frame  int          `json:"frame"`
+	File   string       `json:"file"`
+	Points []trackPoint `json:"points"`
+}
+
+type trackPoint struct {
+	ID    int     `json:"id"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Error float64 `json:"error"`
+}
+
+func runTrack(args []string) error {
+	fs := newFlagSet("track", "[flags] frame frame...")
+	d := addDetectFlags(fs)
+	defaults := tracking.DefaultOptions()
+	window := fs.Int("track-window", defaults.Window, "side of the window matched around each corner")
+	levels := fs.Int("levels", defaults.Levels, "pyramid levels above full resolution")
+	maxError := fs.Float64("max-error", defaults.MaxError, "lose corners whose mean gray difference exceeds this, 0 for no limit")
+	minEigen := fs.Float64("min-eigen", defaults.MinEigen, "lose corners with less texture than this per pixel")
+	format := fs.String("format", "ndjson", "ndjson for one frame per line or json for one array")
+	out := fs.String("o", "-", "output file, - for stdout")
+	if err := parse(fs, args, 2, -1); err != nil {
+		return err
+	}
+	if *format != "ndjson" && *format != "json" {
+		return fmt.Errorf("format must be ndjson or json, not %q", *format)
+	}
+	cfg, err := d.config()
+	if err != nil {
+		return err
+	}
+	opts := defaults
+	opts.Window, opts.Levels, opts.MaxError, opts.MinEigen = *window|1, *levels, *maxError, *minEigen
+
+	img, err := loadImage(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	result, err := detector.Detect(img, cfg)
+	if err != nil {
+		return err
+	}
+	corners, ids := result.Corners, make([]int, len(result.Corners))
+	first := frame{File: fs.Arg(0), Points: make([]trackPoint, len(corners))}
+	for i, c := range corners {
+		ids[i] = i
+		first.Points[i] = trackPoint{ID: i, X: c.X, Y: c.Y}
+	}
+
+	w, err := create(*out)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	enc := json.NewEncoder(w)
+	var frames []frame
+	emit := func(f frame) error {
+		if *format == "json" {
+			frames = append(frames, f)
+			return nil
+		}
+		return enc.Encode(f)
+	}
+	if err := emit(first); err != nil {
+		return err
+	}
+	prev := tracking.NewPyramid(imaging.ToFloat(img), opts)
+	for i, path := range fs.Args()[1:] {
+		img, err := loadImage(path)
+		if err != nil {
+			return err
+		}
+		next := tracking.NewPyramid(imaging.ToFloat(img), opts)
+		f := frame{Frame: i + 1, File: path, Points: []trackPoint{}}
+		kept, keptIDs := corners[:0], ids[:0]
+		for j, p := range tracking.Track(prev, next, corners, opts) {
+			if !p.Found {
+				continue
+			}
+			kept, keptIDs = append(kept, p.Corner), append(keptIDs, ids[j])
+			f.Points = append(f.Points, trackPoint{ID: ids[j], X: p.X, Y: p.Y, Error: p.Error})
+		}
+		if err := emit(f); err != nil {
+			return err
+		}
+		corners, ids, prev = kept, keptIDs, next
+	}
+	if *format == "json" {
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(frames); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
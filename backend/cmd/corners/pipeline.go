@@ -0,0 +1,56 @@
+// pipeline command
+
+package main
+
+import (
+	"Backend/src/pipeline"
+	"fmt"
+	"os"
+)
+
+func runPipeline(args []string) error {
+	fs := newFlagSet("pipeline", "[flags] spec [image...]")
+	output := fs.String("o", ".", "directory the outputs of the spec are written to")
+	check := fs.Bool("check", false, "only validate the spec and print its config hash")
+	example := fs.Bool("example", false, "print an example spec using every stage and exit")
+	quiet := fs.Bool("quiet", false, "do not list the files written on stderr")
+	if err := parse(fs, args, 0, -1); err != nil {
+		return err
+	}
+	if *example {
+		fmt.Print(pipeline.Example)
+		return nil
+	}
+	if fs.NArg() < 1 || (!*check && fs.NArg() < 2) {
+		fs.Usage()
+		return errUsage
+	}
+	spec, err := pipeline.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *check {
+		fmt.Println(spec.Hash())
+		return nil
+	}
+	for _, path := range fs.Args()[1:] {
+		img, err := loadImage(path)
+		if err != nil {
+			return err
+		}
+		detections, written, err := spec.Execute(img, path, *output)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if *quiet {
+			continue
+		}
+		for _, d := range detections {
+			fmt.Fprintf(os.Stderr, "%s %s: %d corners\n", path, d.Label, len(d.Result.Corners))
+		}
+		for _, w := range written {
+			fmt.Fprintf(os.Stderr, "  wrote %s\n", w)
+		}
+	}
+	return nil
+}
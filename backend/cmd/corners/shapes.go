@@ -0,0 +1,122 @@
+// document, edges and contours commands
+
+package main
+
+import (
+	"Backend/src/contour"
+	"Backend/src/corner"
+	"Backend/src/document"
+	"Backend/src/imaging"
+	"fmt"
+)
+
+func runDocument(args []string) error {
+	fs := newFlagSet("document", "[flags] image")
+	aspect := fs.String("aspect", "auto", "page width over height: auto, a4, letter or a number")
+	out := fs.String("o", "-", "page corners json output, - for stdout")
+	rectified := fs.String("image", "", "also save the rectified page, png, jpeg or tiff")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	ratio, err := document.ParseAspect(*aspect)
+	if err != nil {
+		return fmt.Errorf("invalid aspect %q: %w", *aspect, err)
+	}
+	img, err := loadImage(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	quad, err := document.Detect(imaging.ToFloat(img))
+	if err != nil {
+		return err
+	}
+	if *rectified != "" {
+		page, err := document.Rectify(img, quad, ratio)
+		if err != nil {
+			return err
+		}
+		if err := imaging.Save(page, *rectified); err != nil {
+			return err
+		}
+	}
+	return writeJSON(*out, quad)
+}
+
+func runEdges(args []string) error {
+	fs := newFlagSet("edges", "[flags] image")
+	opts := imaging.DefaultLineOptions()
+	fs.Float64Var(&opts.Sigma, "sigma", opts.Sigma, "canny smoothing")
+	fs.Float64Var(&opts.Low, "low", opts.Low, "canny low hysteresis threshold on the sobel magnitude")
+	fs.Float64Var(&opts.High, "high", opts.High, "canny high hysteresis threshold on the sobel magnitude")
+	fs.BoolVar(&opts.Probabilistic, "probabilistic", opts.Probabilistic, "find segments instead of infinite lines")
+	fs.IntVar(&opts.Threshold, "threshold", opts.Threshold, "minimum hough votes")
+	fs.IntVar(&opts.MaxLines, "max-lines", opts.MaxLines, "strongest lines kept by the standard transform")
+	fs.Float64Var(&opts.MinLength, "min-length", opts.MinLength, "shortest segment for the probabilistic transform")
+	fs.Float64Var(&opts.MaxGap, "max-gap", opts.MaxGap, "largest gap bridged inside a segment")
+	fs.Float64Var(&opts.MaxExtend, "max-extend", opts.MaxExtend, "how far segments are extended to meet at a corner")
+	out := fs.String("o", "-", "line intersections json output, - for stdout")
+	edgesPath := fs.String("edges", "", "also save the canny edges, png, jpeg or tiff")
+	linesPath := fs.String("image", "", "also save the image with the lines and their intersections marked")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	img, err := loadImage(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	edges, drawn, corners := imaging.FindLines(img, opts)
+	if *edgesPath != "" {
+		if err := imaging.Save(edges, *edgesPath); err != nil {
+			return err
+		}
+	}
+	if *linesPath != "" {
+		if err := imaging.Save(drawn, *linesPath); err != nil {
+			return err
+		}
+	}
+	if corners == nil {
+		corners = []corner.Corner{}
+	}
+	return writeJSON(*out, map[string]any{"corners": corners})
+}
+
+func runContours(args []string) error {
+	fs := newFlagSet("contours", "[flags] image")
+	opts := contour.DefaultOptions()
+	fs.Float64Var(&opts.Threshold, "threshold", opts.Threshold, "binarization threshold, 0 uses otsu")
+	foreground := fs.String("foreground", "auto", "foreground class: auto, dark or light")
+	fs.IntVar(&opts.MinLength, "min-length", opts.MinLength, "contours with fewer points are ignored")
+	fs.Float64Var(&opts.SigmaHigh, "sigma-high", opts.SigmaHigh, "coarse scale where corners are detected")
+	fs.Float64Var(&opts.SigmaLow, "sigma-low", opts.SigmaLow, "fine scale corners are tracked to")
+	fs.Float64Var(&opts.Curvature, "curvature", opts.Curvature, "minimum absolute curvature at the coarse scale")
+	fs.Float64Var(&opts.Ratio, "ratio", opts.Ratio, "how far a corner must exceed the mean curvature of its region of support")
+	fs.Float64Var(&opts.MaxAngle, "max-angle", opts.MaxAngle, "corners spanning a wider angle in degrees are rejected")
+	fs.Float64Var(&opts.Epsilon, "epsilon", opts.Epsilon, "douglas-peucker tolerance for the polygon approximation")
+	out := fs.String("o", "-", "contours and corners json output, - for stdout")
+	marked := fs.String("image", "", "also save the image with the contours and corners marked")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	switch *foreground {
+	case "auto":
+		opts.Foreground = contour.Auto
+	case "dark":
+		opts.Foreground = contour.Dark
+	case "light":
+		opts.Foreground = contour.Light
+	default:
+		return fmt.Errorf("foreground must be auto, dark or light, not %q", *foreground)
+	}
+	img, err := loadImage(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	contours, corners := contour.Detect(imaging.ToFloat(img), opts)
+	if *marked != "" {
+		if err := imaging.Save(contour.Render(img, contours, corners, opts.MinLength), *marked); err != nil {
+			return err
+		}
+	}
+	return writeJSON(*out, map[string]any{"contours": contours, "corners": corners})
+}
@@ -0,0 +1,137 @@
+// sweep command
+
+package main
+
+import (
+	"Backend/src/batch"
+	"Backend/src/sweep"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+// paramList collects repeated -param flags
+type paramList []sweep.Param
+
+func (p *paramList) String() string {
+	return strings.Join(sweep.Names(*p), ",")
+}
+
+func (p *paramList) Set(value string) error {
+	param, err := sweep.ParseParam(value)
+	if err != nil {
+		return err
+	}
+	if _, ok := paramFlags[param.Name]; ok {
+		// accept the flag spelling of a param too, as in -param min-dist=2:10:2
+		param.Name = paramFlags[param.Name]
+	}
+	*p = append(*p, param)
+	return nil
+}
+
+func runSweep(args []string) error {
+	fs := newFlagSet("sweep", "[flags] -param name=range... file|directory|glob...")
+	d := addDetectFlags(fs)
+	defaults := sweep.DefaultOptions()
+	var params paramList
+	fs.Var(&params, "param", "param to sweep, repeatable: name=min:max:step, name=min:max for random search only, or name=a,b,c")
+	samples := fs.Int("random", 0, "draw this many random configurations instead of the full grid")
+	seed := fs.Int64("seed", 1, "random search seed")
+	metrics := fs.String("metrics", "repeatability,count,runtime", "comma separated metrics in ranking order, the pareto front uses all of them")
+	target := fs.Int("target", 0, "rank by closeness of the mean corner count to this instead of by the most corners")
+	tolerance := fs.Float64("tolerance", defaults.Tolerance, "largest distance in pixels at which a corner counts as repeated")
+	rotate := fs.Float64("rotate", defaults.Rotate, "rotation in degrees of the warped copies used for repeatability")
+	scale := fs.Float64("scale", defaults.Scale, "scale of the warped copies used for repeatability")
+	runs := fs.Int("runs", defaults.Runs, "timed detections per image and configuration, the fastest is kept")
+	out := fs.String("o", "sweep.csv", "ranked csv, - for stdout")
+	paretoPath := fs.String("pareto", "pareto.json", "pareto front summary json, - for stdout")
+	quiet := fs.Bool("quiet", false, "do not report every configuration on stderr")
+	if err := parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+	if len(params) == 0 {
+		return errors.New("give at least one -param to sweep")
+	}
+	if *out == "-" && *paretoPath == "-" {
+		return errors.New("the csv and the pareto summary cannot both go to stdout")
+	}
+	cfg, err := d.config()
+	if err != nil {
+		return err
+	}
+	// a swept param set on the command line too would be overridden anyway
+	for _, p := range params {
+		delete(cfg.Params, p.Name)
+	}
+	opts := sweep.Options{
+		Config: cfg, Params: params, Samples: *samples, Seed: *seed, Target: *target,
+		Tolerance: *tolerance, Rotate: *rotate, Scale: *scale, Runs: *runs,
+	}
+	if opts.Metrics, err = sweep.ParseMetrics(*metrics); err != nil {
+		return err
+	}
+
+	inputs, err := batch.Collect(fs.Args())
+	if err != nil {
+		return err
+	}
+	images := make([]sweep.Image, len(inputs))
+	for i, in := range inputs {
+		img, err := loadImage(in.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", in.Path, err)
+		}
+		if images[i], err = sweep.NewImage(in.Path, img, opts); err != nil {
+			return fmt.Errorf("%s: %w", in.Path, err)
+		}
+	}
+
+	names := sweep.Names(params)
+	done := 0
+	opts.Process = func(t sweep.Trial) {
+		done++
+		if *quiet {
+			return
+		}
+		values := make([]string, len(names))
+		for i, name := range names {
+			values[i] = fmt.Sprintf("%s=%g", name, t.Params[name])
+		}
+		if t.Error != "" {
+			fmt.Fprintf(os.Stderr, "[%d] %s: failed: %s\n", done, strings.Join(values, " "), t.Error)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "[%d] %s: %.1f corners, repeatability %.3f, %.1f ms\n",
+			done, strings.Join(values, " "), t.Count, t.Repeatability, t.Millis)
+	}
+	// an interrupt stops the sweep but still writes what was scored
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	trials, runErr := sweep.Run(ctx, images, opts)
+	if trials == nil {
+		return runErr
+	}
+
+	w, err := create(*out)
+	if err != nil {
+		return err
+	}
+	if err := sweep.WriteCSV(w, trials, names, opts.Metrics); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	summary := sweep.Summarize(trials, len(images), opts)
+	if err := writeJSON(*paretoPath, summary); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d configurations on %d images, %d failed, %d on the pareto front\n",
+		summary.Trials, summary.Images, summary.Failed, len(summary.Pareto))
+	return runErr
+}
@@ -0,0 +1,88 @@
+// watch command
+
+package main
+
+import (
+	"Backend/src/batch"
+	"Backend/src/detector"
+	"Backend/src/watch"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+func runWatch(args []string) error {
+	fs := newFlagSet("watch", "[flags] directory")
+	d := addDetectFlags(fs)
+	defaults := watch.DefaultOptions()
+	names := fs.String("detectors", "", "comma separated detectors run on every image, default the -detector flag")
+	output := fs.String("o", defaults.Output, "output directory, with one tree per detector mirroring the input")
+	format := fs.String("format", string(defaults.Format), "result format: json, csv, ndjson or yaml")
+	withOverlay := fs.Bool("overlay", false, "also render the corners over every image as png")
+	workers := fs.Int("workers", 0, "images processed at once, 0 for one per cpu")
+	interval := fs.Duration("interval", defaults.Interval, "time between two scans of the directory")
+	once := fs.Bool("once", false, "process the images present now and exit instead of watching")
+	quiet := fs.Bool("quiet", false, "do not report every image on stderr")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	cfg, err := d.config()
+	if err != nil {
+		return err
+	}
+	f, err := outputFormat(*format, "")
+	if err != nil {
+		return err
+	}
+	configs := []detector.Config{cfg}
+	if *names != "" {
+		configs = configs[:0]
+		for _, name := range strings.Split(*names, ",") {
+			c := cfg
+			c.Detector = strings.TrimSpace(name)
+			configs = append(configs, c)
+		}
+	}
+
+	opts := watch.Options{
+		Input: fs.Arg(0), Output: *output, Configs: configs, Format: f,
+		Overlay: *withOverlay, Workers: *workers, Interval: *interval,
+	}
+	failed := 0
+	opts.Process = func(e batch.Entry) {
+		if e.Status != batch.StatusOK {
+			failed++
+		}
+		if *quiet {
+			return
+		}
+		if e.Status == batch.StatusOK {
+			fmt.Fprintf(os.Stderr, "ok %s: %d corners in %.0f ms -> %s\n", e.Input, e.Corners, e.Millis, e.Output)
+		} else {
+			fmt.Fprintf(os.Stderr, "failed %s: %s\n", e.Input, e.Error)
+		}
+	}
+	w, err := watch.New(opts)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *once {
+		n, err := w.Poll(ctx, false)
+		fmt.Fprintf(os.Stderr, "%d new images processed, %d files recorded\n", n, w.Processed())
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d detections failed", failed)
+		}
+		return nil
+	}
+	if !*quiet {
+		fmt.Fprintf(os.Stderr, "watching %s every %s, %d files already processed\n", fs.Arg(0), *interval, w.Processed())
+	}
+	return w.Run(ctx)
+}
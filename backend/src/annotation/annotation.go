@@ -0,0 +1,171 @@
+// point annotations exchanged with labeling tools in coco, labelme and cvat formats
+
+package annotation
+
+import (
+	"Backend/src/corner"
+	"Backend/src/detector"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+var (
+	ErrFormat = errors.New("unknown annotation format")
+	ErrSyntax = errors.New("malformed annotation file")
+	ErrImages = errors.New("wrong number of annotated images")
+)
+
+// Label is the class name points are exported under
+const Label = "corner"
+
+// Image is the set of points annotated on one image. Detector names the detector that produced the
+// points, empty for hand labeled ones.
+type Image struct {
+	File     string          `json:"file"`
+	Width    int             `json:"width"`
+	Height   int             `json:"height"`
+	Detector string          `json:"detector,omitempty"`
+	Points   []corner.Corner `json:"points"`
+}
+
+// FromResult wraps a detection of the image file as an annotation, to seed labeling
+func FromResult(file string, result *detector.Result) Image {
+	return Image{File: file, Width: result.Width, Height: result.Height, Detector: result.Detector, Points: result.Corners}
+}
+
+// Find returns the annotation of the named file, compared by base name since tools record paths
+// relative to different roots. A single annotated image is returned whatever its name.
+func Find(images []Image, file string) (Image, bool) {
+	base := filepath.Base(file)
+	for _, img := range images {
+		if path.Base(filepath.ToSlash(img.File)) == base {
+			return img, true
+		}
+	}
+	if len(images) == 1 {
+		return images[0], true
+	}
+	return Image{}, false
+}
+
+type Format string
+
+const (
+	COCO    Format = "coco"    // coco keypoints json, one single keypoint annotation per point
+	LabelMe Format = "labelme" // labelme json, one file per image with a point shape per point
+	CVAT    Format = "cvat"    // cvat for images 1.1 xml
+)
+
+// Formats lists the formats Write and Read accept
+var Formats = []Format{COCO, LabelMe, CVAT}
+
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case COCO, LabelMe, CVAT:
+		return Format(value), nil
+	}
+	return "", fmt.Errorf("%w %q", ErrFormat, value)
+}
+
+// Ext returns the file extension of the format, with its dot
+func (f Format) Ext() string {
+	if f == CVAT {
+		return ".xml"
+	}
+	return ".json"
+}
+
+// Write encodes the annotated images in the given format. LabelMe holds exactly one image per file.
+func Write(w io.Writer, images []Image, format Format) error {
+	switch format {
+	case COCO:
+		return writeCOCO(w, images)
+	case LabelMe:
+		if len(images) != 1 {
+			return fmt.Errorf("%w: labelme holds one image, got %d", ErrImages, len(images))
+		}
+		return writeLabelMe(w, images[0])
+	case CVAT:
+		return writeCVAT(w, images)
+	}
+	return fmt.Errorf("%w %q", ErrFormat, format)
+}
+
+// Read decodes annotated images, telling the format from the content: xml is cvat, and json is
+// labelme when it has shapes and coco otherwise
+func Read(r io.Reader) ([]Image, Format, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	format, err := sniff(data)
+	if err != nil {
+		return nil, "", err
+	}
+	var images []Image
+	switch format {
+	case COCO:
+		images, err = readCOCO(data)
+	case LabelMe:
+		var img Image
+		img, err = readLabelMe(data)
+		images = []Image{img}
+	case CVAT:
+		images, err = readCVAT(data)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return images, format, nil
+}
+
+func sniff(data []byte) (Format, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("<")) {
+		return CVAT, nil
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return "", fmt.Errorf("%w: neither xml nor a json object", ErrFormat)
+	}
+	if _, ok := keys["shapes"]; ok {
+		return LabelMe, nil
+	}
+	if _, ok := keys["annotations"]; ok {
+		return COCO, nil
+	}
+	return "", fmt.Errorf("%w: json without shapes or annotations", ErrFormat)
+}
+
+// Save writes the annotated images to filePath in the given format
+func Save(filePath string, images []Image, format Format) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, images, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads the annotated images in filePath, whatever their format
+func Load(filePath string) ([]Image, Format, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	images, format, err := Read(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", filePath, err)
+	}
+	return images, format, nil
+}
@@ -0,0 +1,121 @@
+// coco keypoint annotations
+
+package annotation
+
+import (
+	"Backend/src/corner"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+type cocoFile struct {
+	Info        cocoInfo         `json:"info"`
+	Images      []cocoImage      `json:"images"`
+	Annotations []cocoAnnotation `json:"annotations"`
+	Categories  []cocoCategory   `json:"categories"`
+}
+
+type cocoInfo struct {
+	Description string `json:"description"`
+	DateCreated string `json:"date_created"`
+}
+
+type cocoImage struct {
+	ID       int    `json:"id"`
+	FileName string `json:"file_name"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+type cocoAnnotation struct {
+	ID           int        `json:"id"`
+	ImageID      int        `json:"image_id"`
+	CategoryID   int        `json:"category_id"`
+	Keypoints    []float64  `json:"keypoints"`
+	NumKeypoints int        `json:"num_keypoints"`
+	Bbox         [4]float64 `json:"bbox"`
+	Area         float64    `json:"area"`
+	Iscrowd      int        `json:"iscrowd"`
+	Score        *float64   `json:"score,omitempty"`
+}
+
+type cocoCategory struct {
+	ID            int      `json:"id"`
+	Name          string   `json:"name"`
+	Supercategory string   `json:"supercategory"`
+	Keypoints     []string `json:"keypoints"`
+	Skeleton      [][2]int `json:"skeleton"`
+}
+
+// writeCOCO writes every point as its own annotation of a single keypoint "corner" category, since
+// coco fixes the keypoint count per category while the number of corners varies. Detected points
+// carry their score, as in coco results, and a box of twice their scale, at least 3 pixels, around
+// them so tools that require boxes accept the file.
+func writeCOCO(w io.Writer, images []Image) error {
+	file := cocoFile{
+		Info:        cocoInfo{Description: "corner annotations", DateCreated: time.Now().UTC().Format(time.RFC3339)},
+		Images:      make([]cocoImage, 0, len(images)),
+		Annotations: []cocoAnnotation{},
+		Categories:  []cocoCategory{{ID: 1, Name: Label, Supercategory: Label, Keypoints: []string{Label}, Skeleton: [][2]int{}}},
+	}
+	for i, img := range images {
+		if img.Detector != "" {
+			file.Info.Description = fmt.Sprintf("corners detected with %s", img.Detector)
+		}
+		file.Images = append(file.Images, cocoImage{ID: i + 1, FileName: img.File, Width: img.Width, Height: img.Height})
+		for _, p := range img.Points {
+			half := math.Max(2*p.Scale, 3)
+			a := cocoAnnotation{
+				ID: len(file.Annotations) + 1, ImageID: i + 1, CategoryID: 1,
+				Keypoints: []float64{p.X, p.Y, 2}, NumKeypoints: 1,
+				Bbox: [4]float64{p.X - half, p.Y - half, 2 * half, 2 * half}, Area: 4 * half * half,
+			}
+			if img.Detector != "" {
+				score := p.Score
+				a.Score = &score
+			}
+			file.Annotations = append(file.Annotations, a)
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(file)
+}
+
+// readCOCO collects the labeled keypoints (visibility above zero) of every annotation, whatever
+// its category or keypoint count, per image in the order the images are listed
+func readCOCO(data []byte) ([]Image, error) {
+	var file cocoFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+	}
+	images := make([]Image, len(file.Images))
+	index := make(map[int]int, len(file.Images))
+	for i, img := range file.Images {
+		images[i] = Image{File: img.FileName, Width: img.Width, Height: img.Height, Points: []corner.Corner{}}
+		index[img.ID] = i
+	}
+	for _, a := range file.Annotations {
+		i, ok := index[a.ImageID]
+		if !ok {
+			return nil, fmt.Errorf("%w: annotation %d refers to missing image %d", ErrSyntax, a.ID, a.ImageID)
+		}
+		if len(a.Keypoints)%3 != 0 {
+			return nil, fmt.Errorf("%w: annotation %d has %d keypoint values", ErrSyntax, a.ID, len(a.Keypoints))
+		}
+		for k := 0; k < len(a.Keypoints); k += 3 {
+			if a.Keypoints[k+2] <= 0 {
+				continue
+			}
+			p := corner.Corner{X: a.Keypoints[k], Y: a.Keypoints[k+1]}
+			if a.Score != nil {
+				p.Score = *a.Score
+			}
+			images[i].Points = append(images[i].Points, p)
+		}
+	}
+	return images, nil
+}
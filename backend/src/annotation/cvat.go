@@ -0,0 +1,111 @@
+// cvat for images 1.1 xml
+
+package annotation
+
+import (
+	"Backend/src/corner"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type cvatFile struct {
+	XMLName xml.Name    `xml:"annotations"`
+	Version string      `xml:"version"`
+	Images  []cvatImage `xml:"image"`
+}
+
+type cvatImage struct {
+	ID     int          `xml:"id,attr"`
+	Name   string       `xml:"name,attr"`
+	Width  int          `xml:"width,attr"`
+	Height int          `xml:"height,attr"`
+	Points []cvatPoints `xml:"points"`
+}
+
+type cvatPoints struct {
+	Label      string          `xml:"label,attr"`
+	Source     string          `xml:"source,attr,omitempty"`
+	Occluded   int             `xml:"occluded,attr"`
+	Points     string          `xml:"points,attr"`
+	ZOrder     int             `xml:"z_order,attr"`
+	Attributes []cvatAttribute `xml:"attribute"`
+}
+
+type cvatAttribute struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// writeCVAT writes a points element per point so each keeps its own score and detector
+// attributes. Detected points are marked with source "auto" as cvat marks its own automatic ones.
+func writeCVAT(w io.Writer, images []Image) error {
+	file := cvatFile{Version: "1.1", Images: make([]cvatImage, len(images))}
+	for i, img := range images {
+		file.Images[i] = cvatImage{ID: i, Name: img.File, Width: img.Width, Height: img.Height, Points: make([]cvatPoints, len(img.Points))}
+		for j, p := range img.Points {
+			points := cvatPoints{Label: Label, Source: "manual", Points: strconv.FormatFloat(p.X, 'g', -1, 64) + "," + strconv.FormatFloat(p.Y, 'g', -1, 64)}
+			if img.Detector != "" {
+				points.Source = "auto"
+				points.Attributes = []cvatAttribute{
+					{Name: "score", Value: strconv.FormatFloat(p.Score, 'g', -1, 64)},
+					{Name: "detector", Value: img.Detector},
+				}
+			}
+			file.Images[i].Points[j] = points
+		}
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(file); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// readCVAT reads every points element of any label, each holding one or more "x,y" pairs
+// separated by semicolons
+func readCVAT(data []byte) ([]Image, error) {
+	var file cvatFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+	}
+	images := make([]Image, len(file.Images))
+	for i, img := range file.Images {
+		images[i] = Image{File: img.Name, Width: img.Width, Height: img.Height, Points: []corner.Corner{}}
+		for _, el := range img.Points {
+			var score float64
+			for _, a := range el.Attributes {
+				switch a.Name {
+				case "score":
+					v, err := strconv.ParseFloat(strings.TrimSpace(a.Value), 64)
+					if err != nil {
+						return nil, fmt.Errorf("%w: score %q", ErrSyntax, a.Value)
+					}
+					score = v
+				case "detector":
+					images[i].Detector = strings.TrimSpace(a.Value)
+				}
+			}
+			for _, pair := range strings.Split(el.Points, ";") {
+				xy := strings.Split(pair, ",")
+				if len(xy) != 2 {
+					return nil, fmt.Errorf("%w: point %q", ErrSyntax, pair)
+				}
+				x, errX := strconv.ParseFloat(strings.TrimSpace(xy[0]), 64)
+				y, errY := strconv.ParseFloat(strings.TrimSpace(xy[1]), 64)
+				if errX != nil || errY != nil {
+					return nil, fmt.Errorf("%w: point %q", ErrSyntax, pair)
+				}
+				images[i].Points = append(images[i].Points, corner.Corner{X: x, Y: y, Score: score})
+			}
+		}
+	}
+	return images, nil
+}
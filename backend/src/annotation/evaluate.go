@@ -0,0 +1,79 @@
+// scores detected corners against ground truth points
+
+package annotation
+
+import (
+	"Backend/src/corner"
+	"math"
+	"sort"
+)
+
+// Evaluation counts how detected corners match ground truth points within a pixel tolerance
+type Evaluation struct {
+	Tolerance float64 `json:"tolerance"`
+	Detected  int     `json:"detected"`
+	Truth     int     `json:"truth"`
+	Matched   int     `json:"matched"`
+	Precision float64 `json:"precision"`  // matched share of the detections
+	Recall    float64 `json:"recall"`     // matched share of the ground truth
+	F1        float64 `json:"f1"`         // harmonic mean of precision and recall
+	MeanError float64 `json:"mean_error"` // mean distance of the matched pairs in pixels
+}
+
+// Evaluate matches detections to ground truth one to one, closest pairs first, counting a pair only
+// when it is at most tolerance pixels apart. With nothing detected precision is 1, and with no
+// ground truth recall is 1, so an empty result on an empty image scores perfectly.
+func Evaluate(detected, truth []corner.Corner, tolerance float64) Evaluation {
+	e := Evaluation{Tolerance: tolerance, Detected: len(detected), Truth: len(truth), Precision: 1, Recall: 1}
+
+	type pair struct {
+		d, t int
+		dist float64
+	}
+	cellSize := math.Max(tolerance, 1)
+	grid := make(map[[2]int][]int)
+	for i, t := range truth {
+		cell := [2]int{int(math.Floor(t.X / cellSize)), int(math.Floor(t.Y / cellSize))}
+		grid[cell] = append(grid[cell], i)
+	}
+	var pairs []pair
+	for i, d := range detected {
+		cx, cy := int(math.Floor(d.X/cellSize)), int(math.Floor(d.Y/cellSize))
+		for gy := cy - 1; gy <= cy+1; gy++ {
+			for gx := cx - 1; gx <= cx+1; gx++ {
+				for _, j := range grid[[2]int{gx, gy}] {
+					if dist := math.Hypot(d.X-truth[j].X, d.Y-truth[j].Y); dist <= tolerance {
+						pairs = append(pairs, pair{i, j, dist})
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		return pairs[a].dist < pairs[b].dist
+	})
+
+	usedD, usedT := make([]bool, len(detected)), make([]bool, len(truth))
+	var sum float64
+	for _, p := range pairs {
+		if usedD[p.d] || usedT[p.t] {
+			continue
+		}
+		usedD[p.d], usedT[p.t] = true, true
+		e.Matched++
+		sum += p.dist
+	}
+	if e.Matched > 0 {
+		e.MeanError = sum / float64(e.Matched)
+	}
+	if e.Detected > 0 {
+		e.Precision = float64(e.Matched) / float64(e.Detected)
+	}
+	if e.Truth > 0 {
+		e.Recall = float64(e.Matched) / float64(e.Truth)
+	}
+	if e.Precision+e.Recall > 0 {
+		e.F1 = 2 * e.Precision * e.Recall / (e.Precision + e.Recall)
+	}
+	return e
+}
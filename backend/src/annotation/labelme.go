@@ -0,0 +1,69 @@
+// labelme point shapes
+
+package annotation
+
+import (
+	"Backend/src/corner"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type labelMeFile struct {
+	Version     string         `json:"version"`
+	Flags       map[string]any `json:"flags"`
+	Shapes      []labelMeShape `json:"shapes"`
+	ImagePath   string         `json:"imagePath"`
+	ImageData   *string        `json:"imageData"`
+	ImageHeight int            `json:"imageHeight"`
+	ImageWidth  int            `json:"imageWidth"`
+}
+
+type labelMeShape struct {
+	Label       string         `json:"label"`
+	Points      [][2]float64   `json:"points"`
+	GroupID     *int           `json:"group_id"`
+	Description string         `json:"description"`
+	ShapeType   string         `json:"shape_type"`
+	Flags       map[string]any `json:"flags"`
+}
+
+// writeLabelMe writes a point shape per point, with the detector that found it as the description
+// and the image referenced rather than embedded
+func writeLabelMe(w io.Writer, img Image) error {
+	file := labelMeFile{
+		Version: "5.2.1", Flags: map[string]any{}, Shapes: make([]labelMeShape, len(img.Points)),
+		ImagePath: img.File, ImageHeight: img.Height, ImageWidth: img.Width,
+	}
+	for i, p := range img.Points {
+		file.Shapes[i] = labelMeShape{
+			Label: Label, Points: [][2]float64{{p.X, p.Y}}, Description: img.Detector,
+			ShapeType: "point", Flags: map[string]any{},
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(file)
+}
+
+// readLabelMe reads the point shapes of any label, skipping other shape types
+func readLabelMe(data []byte) (Image, error) {
+	var file labelMeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Image{}, fmt.Errorf("%w: %v", ErrSyntax, err)
+	}
+	img := Image{File: file.ImagePath, Width: file.ImageWidth, Height: file.ImageHeight, Points: []corner.Corner{}}
+	for _, s := range file.Shapes {
+		if s.ShapeType != "point" {
+			continue
+		}
+		if len(s.Points) != 1 {
+			return Image{}, fmt.Errorf("%w: point shape with %d points", ErrSyntax, len(s.Points))
+		}
+		img.Points = append(img.Points, corner.Corner{X: s.Points[0][0], Y: s.Points[0][1]})
+		if s.Description != "" {
+			img.Detector = s.Description
+		}
+	}
+	return img, nil
+}
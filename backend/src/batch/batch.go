@@ -0,0 +1,373 @@
+// runs a detector over many images with a bounded worker pool, journaling progress so an
+// interrupted run resumes where it stopped
+
+package batch
+
+import (
+	"Backend/src/detector"
+	"Backend/src/imaging"
+	"Backend/src/overlay"
+	"Backend/src/serialize"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoInputs  = errors.New("no input images found")
+	ErrDuplicate = errors.New("inputs map to the same output")
+)
+
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped" // done by an earlier run with the same settings
+)
+
+const (
+	// JournalName is the file in the output directory every finished image is appended to
+	JournalName = "progress.ndjson"
+	// ManifestName is the summary written to the output directory when a run ends
+	ManifestName = "manifest.json"
+)
+
+// Input is an image to process and the path of its outputs relative to the output directory,
+// without extension
+type Input struct {
+	Path string `json:"path"`
+	Rel  string `json:"rel"`
+}
+
+// IsImage tells whether the extension of path is one of the decodable image formats
+func IsImage(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".tif", ".tiff":
+		return true
+	}
+	return false
+}
+
+// Collect expands files, directories and glob patterns into the images to process. Images found
+// in a directory keep their path below it in the output tree, others are placed by base name.
+func Collect(patterns []string) ([]Input, error) {
+	seen := map[string]bool{}
+	var inputs []Input
+	add := func(path, rel string) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		if seen[abs] {
+			return
+		}
+		seen[abs] = true
+		inputs = append(inputs, Input{Path: path, Rel: strings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel))})
+	}
+	walk := func(root string) error {
+		return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !IsImage(path) {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			add(path, rel)
+			return nil
+		})
+	}
+	for _, pattern := range patterns {
+		if info, err := os.Stat(pattern); err == nil {
+			if info.IsDir() {
+				if err := walk(pattern); err != nil {
+					return nil, err
+				}
+			} else {
+				add(pattern, filepath.Base(pattern))
+			}
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", pattern, err)
+		}
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				if err := walk(path); err != nil {
+					return nil, err
+				}
+			} else if IsImage(path) {
+				add(path, filepath.Base(path))
+			}
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, ErrNoInputs
+	}
+	sort.Slice(inputs, func(i, j int) bool {
+		return inputs[i].Rel < inputs[j].Rel
+	})
+	for i := 1; i < len(inputs); i++ {
+		if inputs[i].Rel == inputs[i-1].Rel {
+			return nil, fmt.Errorf("%w: %s and %s", ErrDuplicate, inputs[i-1].Path, inputs[i].Path)
+		}
+	}
+	return inputs, nil
+}
+
+type Options struct {
+	Output  string          // directory the outputs, the journal and the manifest are written to
+	Config  detector.Config // detector and preprocessing applied to every image
+	Format  serialize.Format
+	Overlay bool        // also render the corners over each image as a png
+	Workers int         // images processed at once, 0 for one per cpu
+	Resume  bool        // skip images an earlier run with the same settings finished
+	Process func(Entry) // called with every finished image, from a single goroutine
+}
+
+func DefaultOptions() Options {
+	return Options{Output: "batch", Config: detector.Config{Detector: "harris"}, Format: serialize.JSON, Resume: true}
+}
+
+// Entry records what became of one input
+type Entry struct {
+	Input   string  `json:"input"`
+	Status  string  `json:"status"`
+	Output  string  `json:"output,omitempty"`
+	Overlay string  `json:"overlay,omitempty"`
+	Corners int     `json:"corners"`
+	Millis  float64 `json:"millis"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// Manifest summarizes a run. Entries are sorted by input and include those skipped on resume.
+type Manifest struct {
+	Config    detector.Config  `json:"config"`
+	Format    serialize.Format `json:"format"`
+	Started   time.Time        `json:"started"`
+	Finished  time.Time        `json:"finished"`
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Skipped   int              `json:"skipped"`
+	Entries   []Entry          `json:"entries"`
+}
+
+// settings is the journal header. A journal only resumes a run with equal settings.
+type settings struct {
+	Config  detector.Config  `json:"config"`
+	Format  serialize.Format `json:"format"`
+	Overlay bool             `json:"overlay"`
+}
+
+// readJournal returns the images a previous run with the same settings finished and whose outputs
+// still exist, or nil when there is no journal or it was written with other settings
+func readJournal(path string, header []byte) map[string]Entry {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	if !scanner.Scan() || !bytes.Equal(bytes.TrimSpace(scanner.Bytes()), header) {
+		return nil
+	}
+	done := map[string]Entry{}
+	for scanner.Scan() {
+		var e Entry
+		// a line cut short by a crash is simply redone
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue
+		}
+		if e.Status != StatusOK {
+			delete(done, e.Input)
+			continue
+		}
+		if _, err := os.Stat(e.Output); err != nil {
+			continue
+		}
+		done[e.Input] = e
+	}
+	return done
+}
+
+// Run processes the inputs with a pool of workers. A failing image is recorded in the manifest and
+// the journal without stopping the others. Cancelling ctx stops handing out images, waits for the
+// ones in progress and still writes the manifest, returning it with the context error.
+func Run(ctx context.Context, inputs []Input, opts Options) (*Manifest, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.Format == "" {
+		opts.Format = serialize.JSON
+	}
+	if err := os.MkdirAll(opts.Output, 0o755); err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(settings{Config: opts.Config, Format: opts.Format, Overlay: opts.Overlay})
+	if err != nil {
+		return nil, err
+	}
+
+	journalPath := filepath.Join(opts.Output, JournalName)
+	var done map[string]Entry
+	if opts.Resume {
+		done = readJournal(journalPath, header)
+	}
+	var journal *os.File
+	if done != nil {
+		journal, err = os.OpenFile(journalPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	} else {
+		if journal, err = os.Create(journalPath); err == nil {
+			_, err = fmt.Fprintf(journal, "%s\n", header)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer journal.Close()
+
+	m := &Manifest{Config: opts.Config, Format: opts.Format, Started: time.Now(), Total: len(inputs)}
+	var todo []Input
+	for _, in := range inputs {
+		if e, ok := done[in.Path]; ok {
+			e.Status = StatusSkipped
+			m.Entries = append(m.Entries, e)
+			m.Skipped++
+			continue
+		}
+		todo = append(todo, in)
+	}
+
+	jobs := make(chan Input)
+	results := make(chan Entry)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for in := range jobs {
+				results <- Process(in, opts)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, in := range todo {
+			select {
+			case jobs <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var journalErr error
+	for e := range results {
+		line, _ := json.Marshal(e)
+		if _, err := fmt.Fprintf(journal, "%s\n", line); err != nil && journalErr == nil {
+			journalErr = err
+		}
+		if e.Status == StatusOK {
+			m.Succeeded++
+		} else {
+			m.Failed++
+		}
+		m.Entries = append(m.Entries, e)
+		if opts.Process != nil {
+			opts.Process(e)
+		}
+	}
+	sort.Slice(m.Entries, func(i, j int) bool {
+		return m.Entries[i].Input < m.Entries[j].Input
+	})
+	m.Finished = time.Now()
+
+	if err := writeManifest(filepath.Join(opts.Output, ManifestName), m); err != nil {
+		return m, err
+	}
+	if journalErr != nil {
+		return m, journalErr
+	}
+	return m, ctx.Err()
+}
+
+func writeManifest(path string, m *Manifest) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Process detects the corners of one image and writes its outputs below opts.Output. A detector
+// panic, which some raise on images smaller than their windows, fails the image rather than the run.
+func Process(in Input, opts Options) (e Entry) {
+	start := time.Now()
+	e = Entry{Input: in.Path, Status: StatusFailed}
+	defer func() {
+		if r := recover(); r != nil {
+			e.Status, e.Error = StatusFailed, fmt.Sprint("panic: ", r)
+		}
+		e.Millis = float64(time.Since(start).Microseconds()) / 1000
+	}()
+	img, err := imaging.Load(in.Path)
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	result, err := detector.Detect(img, opts.Config)
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	base := filepath.Join(opts.Output, filepath.FromSlash(in.Rel))
+	if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	if opts.Overlay {
+		overlayPath := base + ".overlay.png"
+		if err := imaging.Save(overlay.Render(img, result.Corners, overlay.DefaultOptions()), overlayPath); err != nil {
+			e.Error = err.Error()
+			return e
+		}
+		e.Overlay = overlayPath
+	}
+	outputPath := base + opts.Format.Ext()
+	if err := serialize.Save(outputPath, result); err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	e.Output, e.Corners, e.Status = outputPath, len(result.Corners), StatusOK
+	return e
+}
@@ -0,0 +1,231 @@
+// camera intrinsic calibration from several chessboard views with zhang's method
+//
+// Every view gives a homography between the board plane and the image. Two constraints per homography
+// on the image of the absolute conic give a closed form estimate of the intrinsics, from which the pose
+// of every view follows. All parameters, including radial and tangential distortion, are then refined
+// together by minimizing the reprojection error with levenberg-marquardt.
+
+package calibration
+
+import (
+	"Backend/src/chessboard"
+	"Backend/src/geometry"
+	"Backend/src/imaging"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+)
+
+var (
+	ErrTooFewViews  = errors.New("calibration needs at least three views of the board")
+	ErrDegenerate   = errors.New("views are degenerate, tilt the board differently between views")
+	ErrSizeMismatch = errors.New("every view must contain the same number of corners as the board")
+)
+
+// Camera holds pinhole intrinsics and the opencv distortion model (k1, k2, p1, p2, k3)
+type Camera struct {
+	Width      int        `json:"image_width"`
+	Height     int        `json:"image_height"`
+	FX         float64    `json:"fx"`
+	FY         float64    `json:"fy"`
+	CX         float64    `json:"cx"`
+	CY         float64    `json:"cy"`
+	Distortion [5]float64 `json:"distortion"`
+}
+
+// View is the pose of the board in one calibration image and its reprojection error in pixels
+type View struct {
+	Name        string     `json:"name,omitempty"`
+	Rotation    [3]float64 `json:"rotation"` // rodrigues vector
+	Translation [3]float64 `json:"translation"`
+	RMS         float64    `json:"rms"`
+}
+
+type Result struct {
+	Camera Camera  `json:"camera"`
+	Views  []View  `json:"views"`
+	RMS    float64 `json:"rms"`
+}
+
+type Options struct {
+	RadialTerms int  // number of radial coefficients estimated, 0 to 3
+	Tangential  bool // estimate p1 and p2
+	Iterations  int  // levenberg-marquardt iteration limit
+}
+
+func DefaultOptions() Options {
+	return Options{RadialTerms: 3, Tangential: true, Iterations: 100}
+}
+
+// BoardPoints returns the board plane coordinates of the inner corners in the row major order
+// used by chessboard.Board
+func BoardPoints(cols, rows int, square float64) []geometry.Point {
+	points := make([]geometry.Point, 0, cols*rows)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			points = append(points, geometry.Point{X: float64(c) * square, Y: float64(r) * square})
+		}
+	}
+	return points
+}
+
+// Calibrate estimates the camera from the image corners of every view, all matching object
+func Calibrate(object []geometry.Point, views [][]geometry.Point, width, height int, opts Options) (*Result, error) {
+	if len(views) < 3 {
+		return nil, ErrTooFewViews
+	}
+	homographies := make([]geometry.Homography, len(views))
+	for i, view := range views {
+		if len(view) != len(object) {
+			return nil, ErrSizeMismatch
+		}
+		h, err := geometry.EstimateHomography(object, view)
+		if err != nil {
+			return nil, fmt.Errorf("view %d: %w", i, err)
+		}
+		homographies[i] = h
+	}
+
+	cam, err := closedFormIntrinsics(homographies)
+	if err != nil {
+		return nil, err
+	}
+	cam.Width, cam.Height = width, height
+
+	poses := make([]pose, len(views))
+	for i, h := range homographies {
+		poses[i] = poseFromHomography(cam, h)
+	}
+
+	cam, poses = refine(cam, poses, object, views, opts)
+
+	result := &Result{Camera: cam, Views: make([]View, len(views))}
+	total := 0.0
+	for i, p := range poses {
+		sum := 0.0
+		for j, o := range object {
+			d := project(cam, p, o).Dist(views[i][j])
+			sum += d * d
+		}
+		total += sum
+		result.Views[i] = View{Rotation: p.rotation, Translation: p.translation, RMS: math.Sqrt(sum / float64(len(object)))}
+	}
+	result.RMS = math.Sqrt(total / float64(len(object)*len(views)))
+	return result, nil
+}
+
+// v returns the row of zhang's constraint matrix built from columns i and j of h
+func v(h geometry.Homography, i, j int) []float64 {
+	hi := [3]float64{h[i], h[3+i], h[6+i]}
+	hj := [3]float64{h[j], h[3+j], h[6+j]}
+	return []float64{
+		hi[0] * hj[0],
+		hi[0]*hj[1] + hi[1]*hj[0],
+		hi[1] * hj[1],
+		hi[2]*hj[0] + hi[0]*hj[2],
+		hi[2]*hj[1] + hi[1]*hj[2],
+		hi[2] * hj[2],
+	}
+}
+
+// closedFormIntrinsics solves V*b = 0 for the image of the absolute conic B = K^-T K^-1 and
+// recovers the intrinsics from it, dropping the skew which real sensors do not have
+func closedFormIntrinsics(homographies []geometry.Homography) (Camera, error) {
+	rows := make([][]float64, 0, 2*len(homographies))
+	for _, h := range homographies {
+		v12 := v(h, 0, 1)
+		v11, v22 := v(h, 0, 0), v(h, 1, 1)
+		diff := make([]float64, 6)
+		for k := range diff {
+			diff[k] = v11[k] - v22[k]
+		}
+		rows = append(rows, v12, diff)
+	}
+	b := geometry.NullVector(rows)
+	b11, b12, b22, b13, b23, b33 := b[0], b[1], b[2], b[3], b[4], b[5]
+
+	den := b11*b22 - b12*b12
+	if den == 0 || b11 == 0 {
+		return Camera{}, ErrDegenerate
+	}
+	cy := (b12*b13 - b11*b23) / den
+	lambda := b33 - (b13*b13+cy*(b12*b13-b11*b23))/b11
+	if lambda/b11 <= 0 || lambda*b11/den <= 0 {
+		return Camera{}, ErrDegenerate
+	}
+	fx := math.Sqrt(lambda / b11)
+	fy := math.Sqrt(lambda * b11 / den)
+	skew := -b12 * fx * fx * fy / lambda
+	cx := skew*cy/fy - b13*fx*fx/lambda
+	return Camera{FX: fx, FY: fy, CX: cx, CY: cy}, nil
+}
+
+type pose struct {
+	rotation    [3]float64
+	translation [3]float64
+}
+
+// poseFromHomography decomposes H = s*K*[r1 r2 t] and projects [r1 r2 r1xr2] onto the nearest rotation
+func poseFromHomography(cam Camera, h geometry.Homography) pose {
+	inv := func(col int) [3]float64 {
+		x, y, w := h[col], h[3+col], h[6+col]
+		return [3]float64{(x - cam.CX*w) / cam.FX, (y - cam.CY*w) / cam.FY, w}
+	}
+	r1, r2, t := inv(0), inv(1), inv(2)
+	s := 1 / norm3(r1)
+	if t[2] < 0 {
+		s = -s // the board has to be in front of the camera
+	}
+	for k := 0; k < 3; k++ {
+		r1[k] *= s
+		r2[k] *= s
+		t[k] *= s
+	}
+	r3 := cross3(r1, r2)
+	m := [3][3]float64{
+		{r1[0], r2[0], r3[0]},
+		{r1[1], r2[1], r3[1]},
+		{r1[2], r2[2], r3[2]},
+	}
+	return pose{rotation: rodrigues(nearestRotation(m)), translation: t}
+}
+
+// CalibrateImages detects a cols x rows board in every image with board and calibrates the camera
+// from the views where it was found. Images without a board are reported in skipped.
+func CalibrateImages(paths []string, cols, rows int, square float64, board chessboard.Options, opts Options) (*Result, map[string]string, error) {
+	skipped := make(map[string]string)
+	views := make([][]geometry.Point, 0, len(paths))
+	names := make([]string, 0, len(paths))
+	width, height := 0, 0
+	for _, path := range paths {
+		img, err := imaging.Load(path)
+		if err != nil {
+			skipped[filepath.Base(path)] = err.Error()
+			continue
+		}
+		b := img.Bounds()
+		if width == 0 {
+			width, height = b.Dx(), b.Dy()
+		} else if b.Dx() != width || b.Dy() != height {
+			skipped[filepath.Base(path)] = "image size differs from the first view"
+			continue
+		}
+		found, err := chessboard.Detect(imaging.ToFloat(img), cols, rows, board)
+		if err != nil {
+			skipped[filepath.Base(path)] = err.Error()
+			continue
+		}
+		views = append(views, found.Points())
+		names = append(names, filepath.Base(path))
+	}
+
+	result, err := Calibrate(BoardPoints(cols, rows, square), views, width, height, opts)
+	if err != nil {
+		return nil, skipped, err
+	}
+	for i := range result.Views {
+		result.Views[i].Name = names[i]
+	}
+	return result, skipped, nil
+}
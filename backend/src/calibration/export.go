@@ -0,0 +1,81 @@
+// calibration export as json and as opencv FileStorage yaml
+
+package calibration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func (r *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func formatFloats(values []float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%.10g", v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func writeMatrix(w io.Writer, name string, rows, cols int, data []float64) {
+	fmt.Fprintf(w, "%s: !!opencv-matrix\n   rows: %d\n   cols: %d\n   dt: d\n   data: [ %s ]\n", name, rows, cols, formatFloats(data))
+}
+
+// WriteYAML writes the calibration in the layout produced by opencv's calibration sample, so it can
+// be read back with cv::FileStorage
+func (r *Result) WriteYAML(w io.Writer) error {
+	var b strings.Builder
+	cam := r.Camera
+	fmt.Fprintf(&b, "%%YAML:1.0\n---\n")
+	fmt.Fprintf(&b, "image_width: %d\nimage_height: %d\n", cam.Width, cam.Height)
+	writeMatrix(&b, "camera_matrix", 3, 3, []float64{cam.FX, 0, cam.CX, 0, cam.FY, cam.CY, 0, 0, 1})
+	writeMatrix(&b, "distortion_coefficients", 5, 1, cam.Distortion[:])
+	fmt.Fprintf(&b, "avg_reprojection_error: %.10g\n", r.RMS)
+
+	perView := make([]float64, len(r.Views))
+	extrinsics := make([]float64, 0, 6*len(r.Views))
+	for i, v := range r.Views {
+		perView[i] = v.RMS
+		extrinsics = append(extrinsics, v.Rotation[:]...)
+		extrinsics = append(extrinsics, v.Translation[:]...)
+	}
+	writeMatrix(&b, "per_view_reprojection_errors", len(r.Views), 1, perView)
+	// rotation vector followed by translation vector for every view, as in the opencv sample
+	writeMatrix(&b, "extrinsic_parameters", len(r.Views), 6, extrinsics)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Save writes the result to filePath as yaml when the extension is .yml or .yaml and as json otherwise
+func (r *Result) Save(filePath string) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if strings.HasSuffix(filePath, ".yml") || strings.HasSuffix(filePath, ".yaml") {
+		return r.WriteYAML(f)
+	}
+	return r.WriteJSON(f)
+}
+
+// Load reads a result previously written as json
+func Load(filePath string) (*Result, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r Result
+	if err := json.NewDecoder(f).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
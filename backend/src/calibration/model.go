@@ -0,0 +1,117 @@
+// projection model and rotation helpers
+
+package calibration
+
+import (
+	"Backend/src/geometry"
+	"math"
+)
+
+func norm3(a [3]float64) float64 {
+	return math.Sqrt(a[0]*a[0] + a[1]*a[1] + a[2]*a[2])
+}
+
+func cross3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+// rotationMatrix converts a rodrigues vector to a rotation matrix
+func rotationMatrix(r [3]float64) [3][3]float64 {
+	theta := norm3(r)
+	if theta < 1e-12 {
+		return [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	}
+	k := [3]float64{r[0] / theta, r[1] / theta, r[2] / theta}
+	c, s := math.Cos(theta), math.Sin(theta)
+	var m [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			m[i][j] = (1 - c) * k[i] * k[j]
+			if i == j {
+				m[i][j] += c
+			}
+		}
+	}
+	m[0][1] -= s * k[2]
+	m[0][2] += s * k[1]
+	m[1][0] += s * k[2]
+	m[1][2] -= s * k[0]
+	m[2][0] -= s * k[1]
+	m[2][1] += s * k[0]
+	return m
+}
+
+// rodrigues converts a rotation matrix to its axis-angle vector
+func rodrigues(m [3][3]float64) [3]float64 {
+	c := (m[0][0] + m[1][1] + m[2][2] - 1) / 2
+	c = math.Max(-1, math.Min(1, c))
+	theta := math.Acos(c)
+	axis := [3]float64{m[2][1] - m[1][2], m[0][2] - m[2][0], m[1][0] - m[0][1]}
+	if theta < 1e-9 {
+		return [3]float64{axis[0] / 2, axis[1] / 2, axis[2] / 2}
+	}
+	if math.Pi-theta < 1e-6 {
+		// sin(theta) vanishes, take the axis from the diagonal of (R + I) / 2 = k k^T
+		k := [3]float64{math.Sqrt(math.Max(0, (m[0][0]+1)/2)), math.Sqrt(math.Max(0, (m[1][1]+1)/2)), math.Sqrt(math.Max(0, (m[2][2]+1)/2))}
+		if m[0][1] < 0 {
+			k[1] = -k[1]
+		}
+		if m[0][2] < 0 {
+			k[2] = -k[2]
+		}
+		return [3]float64{k[0] * theta, k[1] * theta, k[2] * theta}
+	}
+	s := theta / (2 * math.Sin(theta))
+	return [3]float64{axis[0] * s, axis[1] * s, axis[2] * s}
+}
+
+// nearestRotation returns the rotation closest to m in the frobenius norm, m (m^T m)^-1/2
+func nearestRotation(m [3][3]float64) [3][3]float64 {
+	mtm := make([][]float64, 3)
+	for i := range mtm {
+		mtm[i] = make([]float64, 3)
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				mtm[i][j] += m[k][i] * m[k][j]
+			}
+		}
+	}
+	values, vectors := geometry.SymmetricEigen(mtm)
+	var invSqrt [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				invSqrt[i][j] += vectors[i][k] * vectors[j][k] / math.Sqrt(math.Max(values[k], 1e-12))
+			}
+		}
+	}
+	var r [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[i][j] += m[i][k] * invSqrt[k][j]
+			}
+		}
+	}
+	return r
+}
+
+// distort applies the opencv radial and tangential model to normalized image coordinates
+func distort(d [5]float64, x, y float64) (float64, float64) {
+	k1, k2, p1, p2, k3 := d[0], d[1], d[2], d[3], d[4]
+	r2 := x*x + y*y
+	radial := 1 + k1*r2 + k2*r2*r2 + k3*r2*r2*r2
+	xd := x*radial + 2*p1*x*y + p2*(r2+2*x*x)
+	yd := y*radial + p1*(r2+2*y*y) + 2*p2*x*y
+	return xd, yd
+}
+
+// project maps a point on the board plane (z = 0) to pixel coordinates
+func project(cam Camera, p pose, o geometry.Point) geometry.Point {
+	m := rotationMatrix(p.rotation)
+	x := m[0][0]*o.X + m[0][1]*o.Y + p.translation[0]
+	y := m[1][0]*o.X + m[1][1]*o.Y + p.translation[1]
+	z := m[2][0]*o.X + m[2][1]*o.Y + p.translation[2]
+	xd, yd := distort(cam.Distortion, x/z, y/z)
+	return geometry.Point{X: cam.FX*xd + cam.CX, Y: cam.FY*yd + cam.CY}
+}
@@ -0,0 +1,159 @@
+// levenberg-marquardt refinement of intrinsics, distortion and view poses
+
+package calibration
+
+import (
+	"Backend/src/geometry"
+	"math"
+)
+
+// the parameter vector holds fx, fy, cx, cy, k1, k2, p1, p2, k3 followed by six pose values per view
+const cameraParams = 9
+
+func pack(cam Camera, poses []pose) []float64 {
+	params := []float64{cam.FX, cam.FY, cam.CX, cam.CY}
+	params = append(params, cam.Distortion[:]...)
+	for _, p := range poses {
+		params = append(params, p.rotation[:]...)
+		params = append(params, p.translation[:]...)
+	}
+	return params
+}
+
+func unpack(params []float64, cam Camera, poses []pose) (Camera, []pose) {
+	cam.FX, cam.FY, cam.CX, cam.CY = params[0], params[1], params[2], params[3]
+	copy(cam.Distortion[:], params[4:cameraParams])
+	out := make([]pose, len(poses))
+	for i := range out {
+		base := cameraParams + 6*i
+		copy(out[i].rotation[:], params[base:base+3])
+		copy(out[i].translation[:], params[base+3:base+6])
+	}
+	return cam, out
+}
+
+// freeMask marks the parameters that are optimized, distortion terms can be held at zero
+func freeMask(n int, opts Options) []bool {
+	free := make([]bool, n)
+	for i := range free {
+		free[i] = true
+	}
+	radial := []int{4, 5, 8} // k1, k2, k3
+	for i, idx := range radial {
+		free[idx] = i < opts.RadialTerms
+	}
+	free[6], free[7] = opts.Tangential, opts.Tangential
+	return free
+}
+
+func residuals(params []float64, cam Camera, poses []pose, object []geometry.Point, views [][]geometry.Point) []float64 {
+	cam, poses = unpack(params, cam, poses)
+	res := make([]float64, 0, 2*len(object)*len(views))
+	for i, p := range poses {
+		for j, o := range object {
+			q := project(cam, p, o)
+			res = append(res, q.X-views[i][j].X, q.Y-views[i][j].Y)
+		}
+	}
+	return res
+}
+
+func sumSquares(r []float64) float64 {
+	sum := 0.0
+	for _, v := range r {
+		sum += v * v
+	}
+	return sum
+}
+
+// refine minimizes the reprojection error over all free parameters. The jacobian is evaluated by
+// finite differences; a pose only moves the residuals of its own view, so only that block is recomputed.
+func refine(cam Camera, poses []pose, object []geometry.Point, views [][]geometry.Point, opts Options) (Camera, []pose) {
+	params := pack(cam, poses)
+	free := freeMask(len(params), opts)
+	n := len(params)
+	perView := 2 * len(object)
+	r := residuals(params, cam, poses, object, views)
+	cost := sumSquares(r)
+	lambda := 1e-3
+
+	for iter := 0; iter < opts.Iterations; iter++ {
+		jac := make([][]float64, n) // column k holds d r / d params[k]
+		for k := 0; k < n; k++ {
+			if !free[k] {
+				continue
+			}
+			step := 1e-6 * math.Max(1, math.Abs(params[k]))
+			old := params[k]
+			params[k] = old + step
+			shifted := residuals(params, cam, poses, object, views)
+			params[k] = old
+			col := make([]float64, len(r))
+			lo, hi := 0, len(r)
+			if k >= cameraParams {
+				view := (k - cameraParams) / 6
+				lo, hi = view*perView, (view+1)*perView
+			}
+			for i := lo; i < hi; i++ {
+				col[i] = (shifted[i] - r[i]) / step
+			}
+			jac[k] = col
+		}
+
+		jtj := make([][]float64, n)
+		jtr := make([]float64, n)
+		for a := 0; a < n; a++ {
+			jtj[a] = make([]float64, n)
+			if jac[a] == nil {
+				jtj[a][a] = 1
+				continue
+			}
+			for i, v := range jac[a] {
+				jtr[a] -= v * r[i]
+			}
+			for b := 0; b < n; b++ {
+				if jac[b] == nil {
+					continue
+				}
+				sum := 0.0
+				for i, v := range jac[a] {
+					sum += v * jac[b][i]
+				}
+				jtj[a][b] = sum
+			}
+		}
+
+		improved := false
+		for attempt := 0; attempt < 10; attempt++ {
+			a := make([][]float64, n)
+			for i := range a {
+				a[i] = append([]float64(nil), jtj[i]...)
+				if jac[i] != nil {
+					a[i][i] += lambda * jtj[i][i]
+				}
+			}
+			delta, err := geometry.Solve(a, append([]float64(nil), jtr...))
+			if err != nil {
+				lambda *= 10
+				continue
+			}
+			candidate := make([]float64, n)
+			for i := range candidate {
+				candidate[i] = params[i] + delta[i]
+			}
+			nr := residuals(candidate, cam, poses, object, views)
+			if nc := sumSquares(nr); nc < cost {
+				converged := (cost-nc)/cost < 1e-12
+				params, r, cost = candidate, nr, nc
+				lambda = math.Max(lambda/10, 1e-12)
+				improved = !converged
+				break
+			}
+			lambda *= 10
+		}
+		if !improved {
+			break
+		}
+	}
+	return unpack(params, cam, poses)
+}
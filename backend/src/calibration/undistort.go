@@ -0,0 +1,32 @@
+// removal of lens distortion using a calibrated camera
+
+package calibration
+
+import (
+	"Backend/src/imaging"
+	"image"
+)
+
+// Undistort resamples img so that straight lines in the scene are straight in the output. The output
+// keeps the size and camera matrix of the input; every output pixel is mapped through the distortion
+// model to find where it was imaged and sampled bilinearly.
+func Undistort(img image.Image, cam Camera) *image.RGBA {
+	bounds := img.Bounds()
+	return imaging.Warp(img, bounds.Dx(), bounds.Dy(), func(u, v float64) (float64, float64) {
+		xd, yd := distort(cam.Distortion, (u-cam.CX)/cam.FX, (v-cam.CY)/cam.FY)
+		return cam.FX*xd + cam.CX, cam.FY*yd + cam.CY
+	})
+}
+
+// UndistortFile undistorts the image at inputPath with the calibration stored at calibrationPath
+func UndistortFile(inputPath, outputPath, calibrationPath string) error {
+	result, err := Load(calibrationPath)
+	if err != nil {
+		return err
+	}
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		return err
+	}
+	return imaging.Save(Undistort(img, result.Camera), outputPath)
+}
@@ -0,0 +1,247 @@
+// chessboard inner corner detection for camera calibration targets
+//
+// Inner corners of a chessboard are saddle points of the intensity surface, so candidates are taken
+// from the maxima of the negative hessian determinant. A grid is then grown from a 2x2 seed by
+// predicting every new row or column from the previous two, which tolerates perspective, and the
+// grid is accepted when it has the requested size. Corner positions are refined to sub-pixel
+// accuracy with the gradient orthogonality condition used by opencv's cornerSubPix.
+
+package chessboard
+
+import (
+	"Backend/src/corner"
+	"Backend/src/geometry"
+	"Backend/src/imaging"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+var (
+	ErrInvalidSize      = errors.New("board must have at least 2x2 inner corners")
+	ErrTooFewCandidates = errors.New("not enough saddle point candidates")
+	ErrNoGrid           = errors.New("no chessboard grid found among the candidates")
+	ErrSizeMismatch     = errors.New("detected grid does not match the requested board size")
+)
+
+type Options struct {
+	Sigma         float64 // smoothing scale of the hessian used for saddle candidates
+	Threshold     float64 // fraction of the strongest saddle response a candidate must reach
+	MaxCandidates int     // strongest candidates considered for grid fitting
+	RefineRadius  int     // half size of the sub-pixel refinement window, 0 disables refinement
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Sigma:         2,
+		Threshold:     0.05,
+		MaxCandidates: 400,
+		RefineRadius:  5,
+	}
+}
+
+// Board is a detected chessboard, with corners stored row major so Corners[r*Cols+c] is
+// the corner in row r and column c. Rows run downwards and columns to the right of the image.
+type Board struct {
+	Cols    int             `json:"cols"`
+	Rows    int             `json:"rows"`
+	Corners []corner.Corner `json:"corners"`
+}
+
+func (b *Board) At(row, col int) corner.Corner {
+	return b.Corners[row*b.Cols+col]
+}
+
+// Points returns the corner positions in row major order
+func (b *Board) Points() []geometry.Point {
+	points := make([]geometry.Point, len(b.Corners))
+	for i, c := range b.Corners {
+		points[i] = geometry.Point{X: c.X, Y: c.Y}
+	}
+	return points
+}
+
+// Detect finds the cols x rows inner corners of a chessboard, returning an error that explains
+// why the board was rejected when it is not found
+func Detect(gray *imaging.Float, cols, rows int, opts Options) (*Board, error) {
+	if cols < 2 || rows < 2 {
+		return nil, ErrInvalidSize
+	}
+	candidates := saddleCandidates(gray, opts)
+	if len(candidates) < cols*rows {
+		return nil, fmt.Errorf("%w: found %d, need %d", ErrTooFewCandidates, len(candidates), cols*rows)
+	}
+
+	points := make([]geometry.Point, len(candidates))
+	for i, c := range candidates {
+		points[i] = geometry.Point{X: c.X, Y: c.Y}
+	}
+
+	bestRows, bestCols := 0, 0
+	for seed := range points {
+		cells := growGrid(points, seed)
+		if cells == nil {
+			continue
+		}
+		r, c := len(cells), len(cells[0])
+		if r == cols && c == rows && r != c {
+			cells = transpose(cells)
+			r, c = c, r
+		}
+		if r == rows && c == cols {
+			cells = orient(cells, points)
+			board := &Board{Cols: cols, Rows: rows, Corners: make([]corner.Corner, 0, cols*rows)}
+			for _, row := range cells {
+				for _, idx := range row {
+					board.Corners = append(board.Corners, candidates[idx])
+				}
+			}
+			refineBoard(gray, board, opts.RefineRadius)
+			return board, nil
+		}
+		if r*c > bestRows*bestCols {
+			bestRows, bestCols = r, c
+		}
+	}
+	if bestRows == 0 {
+		return nil, ErrNoGrid
+	}
+	return nil, fmt.Errorf("%w: largest grid is %dx%d, expected %dx%d", ErrSizeMismatch, bestCols, bestRows, cols, rows)
+}
+
+// saddleCandidates returns the local maxima of lxy^2 - lxx*lyy, which is positive only at saddle points
+func saddleCandidates(gray *imaging.Float, opts Options) []corner.Corner {
+	smooth := imaging.Gaussian(gray, opts.Sigma)
+	lxx, lyy, lxy := imaging.SecondDerivatives(smooth)
+	saddle := imaging.NewFloat(gray.W, gray.H)
+	for i := range saddle.Pix {
+		saddle.Pix[i] = lxy.Pix[i]*lxy.Pix[i] - lxx.Pix[i]*lyy.Pix[i]
+	}
+	_, max := saddle.MinMax()
+	if max <= 0 {
+		return nil
+	}
+
+	border := int(math.Ceil(3 * opts.Sigma))
+	candidates := make([]corner.Corner, 0)
+	for _, p := range imaging.LocalMaxima(saddle, opts.Threshold*max, border) {
+		if !isXJunction(gray, float64(p[0]), float64(p[1]), 2*opts.Sigma) {
+			continue
+		}
+		candidates = append(candidates, corner.Corner{X: float64(p[0]), Y: float64(p[1]), Score: saddle.At(p[0], p[1])})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	// a blurred saddle can produce several maxima, keep the strongest within the smoothing radius
+	minDist := 2 * opts.Sigma
+	kept := make([]corner.Corner, 0, len(candidates))
+	for _, c := range candidates {
+		close := false
+		for _, k := range kept {
+			if math.Hypot(c.X-k.X, c.Y-k.Y) < minDist {
+				close = true
+				break
+			}
+		}
+		if !close {
+			kept = append(kept, c)
+			if opts.MaxCandidates > 0 && len(kept) == opts.MaxCandidates {
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// isXJunction checks that a circle around the candidate crosses exactly four light and dark
+// sectors. The corners on the outside of the board also have a saddle response but only two sectors.
+func isXJunction(gray *imaging.Float, x, y, radius float64) bool {
+	const samples = 32
+	values := make([]float64, samples)
+	mean := 0.0
+	for i := range values {
+		angle := 2 * math.Pi * float64(i) / samples
+		values[i] = gray.Bilinear(x+radius*math.Cos(angle), y+radius*math.Sin(angle))
+		mean += values[i]
+	}
+	mean /= samples
+	changes := 0
+	for i := range values {
+		if (values[i] > mean) != (values[(i+1)%samples] > mean) {
+			changes++
+		}
+	}
+	return changes == 4
+}
+
+// refineBoard moves every corner to the point where the surrounding gradients are orthogonal
+// to the vector joining them to the corner
+func refineBoard(gray *imaging.Float, board *Board, radius int) {
+	if radius <= 0 {
+		return
+	}
+	dx, dy := imaging.Derivatives(imaging.Gaussian(gray, 1))
+	for i, c := range board.Corners {
+		p := RefineCorner(dx, dy, geometry.Point{X: c.X, Y: c.Y}, radius)
+		board.Corners[i].X, board.Corners[i].Y = p.X, p.Y
+	}
+}
+
+// RefineCorner refines a saddle or corner position to sub-pixel accuracy given the image gradients
+func RefineCorner(dx, dy *imaging.Float, start geometry.Point, radius int) geometry.Point {
+	p := start
+	sigma := float64(radius) / 2
+	for iter := 0; iter < 20; iter++ {
+		cx, cy := int(math.Round(p.X)), int(math.Round(p.Y))
+		var a11, a12, a22, b1, b2 float64
+		for j := -radius; j <= radius; j++ {
+			for i := -radius; i <= radius; i++ {
+				x, y := cx+i, cy+j
+				if x < 0 || y < 0 || x >= dx.W || y >= dx.H {
+					continue
+				}
+				w := math.Exp(-float64(i*i+j*j) / (2 * sigma * sigma))
+				gx, gy := dx.At(x, y), dy.At(x, y)
+				gxx, gxy, gyy := w*gx*gx, w*gx*gy, w*gy*gy
+				a11 += gxx
+				a12 += gxy
+				a22 += gyy
+				b1 += gxx*float64(x) + gxy*float64(y)
+				b2 += gxy*float64(x) + gyy*float64(y)
+			}
+		}
+		det := a11*a22 - a12*a12
+		if math.Abs(det) < 1e-9 {
+			break
+		}
+		next := geometry.Point{X: (a22*b1 - a12*b2) / det, Y: (a11*b2 - a12*b1) / det}
+		if next.Dist(start) > float64(radius) {
+			return start
+		}
+		moved := next.Dist(p)
+		p = next
+		if moved < 0.01 {
+			break
+		}
+	}
+	return p
+}
+
+// Chessboard detects a cols x rows board in the image at inputPath and saves the marked corners
+func Chessboard(inputPath, outputPath string, cols, rows int) (*Board, error) {
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	board, err := Detect(imaging.ToFloat(img), cols, rows, DefaultOptions())
+	if err != nil {
+		return nil, err
+	}
+	if err := imaging.Save(corner.Draw(img, board.Corners), outputPath); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
@@ -0,0 +1,110 @@
+package chessboard
+
+import (
+	"Backend/src/geometry"
+	"errors"
+	"math"
+	"testing"
+)
+
+// view returns the homography placing the board origin at (tx, ty), rotated by degrees and with a
+// perspective tilt of px, py
+func view(degrees, tx, ty, px, py float64) geometry.Homography {
+	a := degrees * math.Pi / 180
+	c, s := math.Cos(a), math.Sin(a)
+	return geometry.Homography{
+		c, -s, tx,
+		s, c, ty,
+		px, py, 1,
+	}
+}
+
+func TestDetectRenderedBoards(t *testing.T) {
+	tests := []struct {
+		name       string
+		cols, rows int
+		h          geometry.Homography
+	}{
+		{"fronto-parallel", 7, 5, view(0, 40, 40, 0, 0)},
+		{"rotated", 7, 5, view(15, 90, 30, 0, 0)},
+		{"rotated the other way", 6, 4, view(-20, 40, 110, 0, 0)},
+		{"tilted away on the right", 7, 5, view(5, 50, 50, 0.0006, 0)},
+		{"tilted away at the bottom", 7, 5, view(-5, 60, 60, 0, 0.0008)},
+		{"tilted on both axes", 8, 6, view(10, 80, 40, 0.0004, 0.0005)},
+	}
+	const tolerance = 0.5
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, truth, err := Render(tt.cols, tt.rows, 30, 400, 320, tt.h)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			board, err := Detect(img, tt.cols, tt.rows, DefaultOptions())
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			if board.Cols != tt.cols || board.Rows != tt.rows || len(board.Corners) != len(truth) {
+				t.Fatalf("got a %dx%d board with %d corners, want %dx%d with %d",
+					board.Cols, board.Rows, len(board.Corners), tt.cols, tt.rows, len(truth))
+			}
+			worst := 0.0
+			for i, p := range board.Points() {
+				d := p.Dist(truth[i])
+				worst = math.Max(worst, d)
+				if d > tolerance {
+					t.Errorf("corner %d (row %d, col %d) at %.2f,%.2f, want %.2f,%.2f (%.2f px off)",
+						i, i/tt.cols, i%tt.cols, p.X, p.Y, truth[i].X, truth[i].Y, d)
+				}
+			}
+			t.Logf("largest error %.3f px", worst)
+		})
+	}
+}
+
+func TestDetectRejectsBoards(t *testing.T) {
+	occluded, _, err := Render(7, 5, 30, 400, 320, view(0, 40, 40, 0, 0))
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	// cover the right half of the board, hiding three columns of inner corners
+	for y := 0; y < occluded.H; y++ {
+		for x := 170; x < occluded.W; x++ {
+			occluded.Set(x, y, lightLevel)
+		}
+	}
+	board, _, err := Render(7, 5, 30, 400, 320, view(0, 40, 40, 0, 0))
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		cols, rows int
+		occluded   bool
+		want       []error
+	}{
+		{"occluded", 7, 5, true, []error{ErrSizeMismatch, ErrNoGrid, ErrTooFewCandidates}},
+		{"smaller than the board", 5, 4, false, []error{ErrSizeMismatch}},
+		{"larger than the board", 9, 6, false, []error{ErrSizeMismatch, ErrTooFewCandidates}},
+		{"invalid size", 1, 5, false, []error{ErrInvalidSize}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := board
+			if tt.occluded {
+				img = occluded
+			}
+			got, err := Detect(img, tt.cols, tt.rows, DefaultOptions())
+			if err == nil {
+				t.Fatalf("found a %dx%d board, want an error", got.Cols, got.Rows)
+			}
+			for _, want := range tt.want {
+				if errors.Is(err, want) {
+					t.Logf("rejected: %v", err)
+					return
+				}
+			}
+			t.Errorf("error %q is none of %v", err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// grid hypothesis growing and ordering for chessboard candidates
+
+package chessboard
+
+import (
+	"Backend/src/geometry"
+	"math"
+	"sort"
+)
+
+// matchTolerance is the fraction of the local grid spacing a predicted corner may be off by
+const matchTolerance = 0.4
+
+type gridBuilder struct {
+	points []geometry.Point
+	used   []bool
+	cells  [][]int
+}
+
+// nearest returns the closest unused point within tol of p, or -1
+func (g *gridBuilder) nearest(p geometry.Point, tol float64) int {
+	best, bestDist := -1, tol
+	for i, q := range g.points {
+		if g.used[i] {
+			continue
+		}
+		if d := q.Dist(p); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// growGrid builds the largest regular grid reachable from the seed point, or nil if the seed
+// does not have two non parallel neighbours that close a quadrilateral
+func growGrid(points []geometry.Point, seed int) [][]int {
+	g := &gridBuilder{points: points, used: make([]bool, len(points))}
+	g.used[seed] = true
+
+	neighbours := make([]int, 0, len(points)-1)
+	for i := range points {
+		if i != seed {
+			neighbours = append(neighbours, i)
+		}
+	}
+	sort.Slice(neighbours, func(i, j int) bool {
+		return points[neighbours[i]].Dist(points[seed]) < points[neighbours[j]].Dist(points[seed])
+	})
+	if len(neighbours) < 3 {
+		return nil
+	}
+
+	s := points[seed]
+	n1 := neighbours[0]
+	d1 := points[n1].Sub(s)
+	n2 := -1
+	for _, n := range neighbours[1:min(len(neighbours), 8)] {
+		d := points[n].Sub(s)
+		if math.Abs(d.Dot(d1))/(d.Norm()*d1.Norm()) < 0.8 {
+			n2 = n
+			break
+		}
+	}
+	if n2 < 0 {
+		return nil
+	}
+	d2 := points[n2].Sub(s)
+	g.used[n1], g.used[n2] = true, true
+	n3 := g.nearest(s.Add(d1).Add(d2), matchTolerance*math.Min(d1.Norm(), d2.Norm()))
+	if n3 < 0 {
+		return nil
+	}
+	g.used[n3] = true
+	g.cells = [][]int{{seed, n1}, {n2, n3}}
+
+	for g.growRow(false) || g.growRow(true) || g.growCol(false) || g.growCol(true) {
+	}
+	return g.cells
+}
+
+// predictLine extrapolates a new line of points from the last two lines, claiming matches only
+// if every position of the line is found
+func (g *gridBuilder) predictLine(last, prev []int) []int {
+	found := make([]int, len(last))
+	for i := range last {
+		a, b := g.points[last[i]], g.points[prev[i]]
+		step := a.Sub(b)
+		idx := g.nearest(a.Add(step), matchTolerance*step.Norm())
+		if idx < 0 {
+			for _, f := range found[:i] {
+				g.used[f] = false
+			}
+			return nil
+		}
+		g.used[idx] = true
+		found[i] = idx
+	}
+	return found
+}
+
+func (g *gridBuilder) growRow(top bool) bool {
+	n := len(g.cells)
+	var line []int
+	if top {
+		line = g.predictLine(g.cells[0], g.cells[1])
+	} else {
+		line = g.predictLine(g.cells[n-1], g.cells[n-2])
+	}
+	if line == nil {
+		return false
+	}
+	if top {
+		g.cells = append([][]int{line}, g.cells...)
+	} else {
+		g.cells = append(g.cells, line)
+	}
+	return true
+}
+
+func (g *gridBuilder) growCol(left bool) bool {
+	n := len(g.cells[0])
+	last := make([]int, len(g.cells))
+	prev := make([]int, len(g.cells))
+	for r, row := range g.cells {
+		if left {
+			last[r], prev[r] = row[0], row[1]
+		} else {
+			last[r], prev[r] = row[n-1], row[n-2]
+		}
+	}
+	line := g.predictLine(last, prev)
+	if line == nil {
+		return false
+	}
+	for r := range g.cells {
+		if left {
+			g.cells[r] = append([]int{line[r]}, g.cells[r]...)
+		} else {
+			g.cells[r] = append(g.cells[r], line[r])
+		}
+	}
+	return true
+}
+
+func transpose(cells [][]int) [][]int {
+	out := make([][]int, len(cells[0]))
+	for c := range out {
+		out[c] = make([]int, len(cells))
+		for r := range cells {
+			out[c][r] = cells[r][c]
+		}
+	}
+	return out
+}
+
+// orient flips the grid so columns run to the right and rows run downwards in the image
+func orient(cells [][]int, points []geometry.Point) [][]int {
+	rows, cols := len(cells), len(cells[0])
+	colDir := points[cells[0][cols-1]].Sub(points[cells[0][0]])
+	if colDir.X < 0 {
+		for _, row := range cells {
+			for i, j := 0, cols-1; i < j; i, j = i+1, j-1 {
+				row[i], row[j] = row[j], row[i]
+			}
+		}
+		colDir = colDir.Scale(-1)
+	}
+	rowDir := points[cells[rows-1][0]].Sub(points[cells[0][0]])
+	if colDir.Cross(rowDir) < 0 {
+		for i, j := 0, rows-1; i < j; i, j = i+1, j-1 {
+			cells[i], cells[j] = cells[j], cells[i]
+		}
+	}
+	return cells
+}
@@ -0,0 +1,60 @@
+// synthetic chessboard rendering, used to check the detector against known corner positions
+
+package chessboard
+
+import (
+	"Backend/src/geometry"
+	"Backend/src/imaging"
+)
+
+const (
+	darkLevel  = 30
+	lightLevel = 220
+)
+
+// Render draws a board with cols x rows inner corners and squares of the given size, seen through
+// the homography h that maps board plane coordinates to pixels. The board plane origin is the outer
+// top left corner of the board. It returns the image and the true inner corners in row major order.
+func Render(cols, rows int, square float64, width, height int, h geometry.Homography) (*imaging.Float, []geometry.Point, error) {
+	inv, err := h.Inverse()
+	if err != nil {
+		return nil, nil, err
+	}
+	img := imaging.NewFloat(width, height)
+	const samples = 4 // supersampling per axis for anti-aliased edges
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					p := inv.Apply(geometry.Point{
+						X: float64(x) + (float64(sx)+0.5)/samples - 0.5,
+						Y: float64(y) + (float64(sy)+0.5)/samples - 0.5,
+					})
+					sum += boardLevel(p.X/square, p.Y/square, cols, rows)
+				}
+			}
+			img.Set(x, y, sum/(samples*samples))
+		}
+	}
+
+	corners := make([]geometry.Point, 0, cols*rows)
+	for r := 1; r <= rows; r++ {
+		for c := 1; c <= cols; c++ {
+			corners = append(corners, h.Apply(geometry.Point{X: float64(c) * square, Y: float64(r) * square}))
+		}
+	}
+	return img, corners, nil
+}
+
+// boardLevel returns the intensity at (u, v) measured in squares; the board has (cols+1) x (rows+1)
+// squares and everything around it is light
+func boardLevel(u, v float64, cols, rows int) float64 {
+	if u < 0 || v < 0 || u >= float64(cols+1) || v >= float64(rows+1) {
+		return lightLevel
+	}
+	if (int(u)+int(v))%2 == 0 {
+		return darkLevel
+	}
+	return lightLevel
+}
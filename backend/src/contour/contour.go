@@ -0,0 +1,201 @@
+// contour extraction from binary images with suzuki-abe border following
+
+package contour
+
+import (
+	"Backend/src/imaging"
+	"image"
+)
+
+// Contour is a closed border of a connected component. Holes are the inner borders of a component;
+// Parent is the ID of the enclosing contour or -1 for outermost borders.
+type Contour struct {
+	ID      int           `json:"id"`
+	Parent  int           `json:"parent"`
+	Hole    bool          `json:"hole"`
+	Points  []image.Point `json:"points"`
+	Polygon []image.Point `json:"polygon,omitempty"`
+}
+
+type Foreground int
+
+const (
+	Auto  Foreground = iota // whichever class covers less of the image
+	Dark                    // pixels at or below the threshold
+	Light                   // pixels above the threshold
+)
+
+// Binarize marks foreground pixels with 1. A threshold <= 0 is chosen with otsu's method.
+func Binarize(gray *imaging.Float, threshold float64, fg Foreground) []int {
+	if threshold <= 0 {
+		threshold = imaging.Otsu(gray)
+	}
+	light := 0
+	for _, v := range gray.Pix {
+		if v > threshold {
+			light++
+		}
+	}
+	if fg == Auto {
+		fg = Dark
+		if light < len(gray.Pix)-light {
+			fg = Light
+		}
+	}
+	binary := make([]int, len(gray.Pix))
+	for i, v := range gray.Pix {
+		if (v > threshold) == (fg == Light) {
+			binary[i] = 1
+		}
+	}
+	return binary
+}
+
+// neighbours in clockwise order on screen (y pointing down), starting east
+var neighbours = [8][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+
+func direction(dx, dy int) int {
+	for i, n := range neighbours {
+		if n[0] == dx && n[1] == dy {
+			return i
+		}
+	}
+	return -1
+}
+
+// FindContours follows every outer and hole border of the binary image (non zero is foreground)
+// using the algorithm of suzuki and abe (1985), returning contours in raster order of their start
+func FindContours(binary []int, w, h int) []Contour {
+	// pad with a background frame so border following never leaves the image
+	pw, ph := w+2, h+2
+	f := make([]int, pw*ph)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if binary[y*w+x] != 0 {
+				f[(y+1)*pw+x+1] = 1
+			}
+		}
+	}
+	at := func(x, y int) int { return f[y*pw+x] }
+
+	contours := make([]Contour, 0)
+	// border numbers start at 2, number 1 stands for the frame which acts as a hole border
+	isHole := map[int]bool{1: true}
+	parentOf := map[int]int{1: -1}
+	nbd := 1
+
+	for y := 1; y < ph-1; y++ {
+		lnbd := 1
+		for x := 1; x < pw-1; x++ {
+			v := at(x, y)
+			var fromX, fromY int
+			hole := false
+			switch {
+			case v == 1 && at(x-1, y) == 0:
+				fromX, fromY = x-1, y
+			case v >= 1 && at(x+1, y) == 0:
+				hole = true
+				fromX, fromY = x+1, y
+				if v > 1 {
+					lnbd = v
+				}
+			default:
+				if v != 0 && v != 1 {
+					lnbd = abs(v)
+				}
+				continue
+			}
+
+			nbd++
+			isHole[nbd] = hole
+			if hole == isHole[lnbd] {
+				parentOf[nbd] = parentOf[lnbd]
+			} else {
+				parentOf[nbd] = lnbd
+			}
+			points := follow(f, pw, x, y, fromX, fromY, nbd)
+			for i := range points {
+				points[i] = points[i].Sub(image.Pt(1, 1))
+			}
+			contours = append(contours, Contour{Hole: hole, Points: points, Parent: parentOf[nbd]})
+
+			if v := at(x, y); v != 1 {
+				lnbd = abs(v)
+			}
+		}
+	}
+	// border numbers map to contour IDs by an offset of 2, the frame has no contour
+	for i := range contours {
+		contours[i].ID = i
+		if contours[i].Parent >= 2 {
+			contours[i].Parent -= 2
+		} else {
+			contours[i].Parent = -1
+		}
+	}
+	return contours
+}
+
+// follow traces one border starting at (x, y) whose background neighbour is (fromX, fromY),
+// labelling it with nbd as in steps 3.1 to 3.5 of the paper
+func follow(f []int, pw, x, y, fromX, fromY, nbd int) []image.Point {
+	at := func(px, py int) int { return f[py*pw+px] }
+	start := direction(fromX-x, fromY-y)
+
+	// 3.1 search clockwise for the first non zero neighbour
+	first := -1
+	for k := 0; k < 8; k++ {
+		d := (start + k) % 8
+		if at(x+neighbours[d][0], y+neighbours[d][1]) != 0 {
+			first = d
+			break
+		}
+	}
+	if first < 0 {
+		f[y*pw+x] = -nbd
+		return []image.Point{image.Pt(x, y)}
+	}
+
+	x1, y1 := x+neighbours[first][0], y+neighbours[first][1]
+	x2, y2 := x1, y1
+	x3, y3 := x, y
+	points := make([]image.Point, 0)
+	for {
+		points = append(points, image.Pt(x3, y3))
+		// 3.3 search counter clockwise starting after (x2, y2)
+		d := direction(x2-x3, y2-y3)
+		eastExamined := false
+		var x4, y4 int
+		for k := 1; k <= 8; k++ {
+			nd := (d - k + 16) % 8
+			nx, ny := x3+neighbours[nd][0], y3+neighbours[nd][1]
+			if at(nx, ny) != 0 {
+				x4, y4 = nx, ny
+				break
+			}
+			if nd == 0 {
+				eastExamined = true
+			}
+		}
+		// 3.4 label the border pixel
+		if eastExamined {
+			f[y3*pw+x3] = -nbd
+		} else if at(x3, y3) == 1 {
+			f[y3*pw+x3] = nbd
+		}
+		// 3.5 stop when back at the start with the same next pixel
+		if x4 == x && y4 == y && x3 == x1 && y3 == y1 {
+			break
+		}
+		x2, y2 = x3, y3
+		x3, y3 = x4, y4
+	}
+	return points
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
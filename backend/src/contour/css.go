@@ -0,0 +1,232 @@
+// curvature scale space corner detection on closed contours
+//
+// Following he and yung (2004), curvature maxima are found on the contour smoothed at a coarse
+// scale, where noise and small wiggles are gone, and then tracked down to a fine scale for accurate
+// localization. A maximum is kept if its curvature is above an absolute threshold, clearly above the
+// mean curvature of its region of support (which rejects round corners) and if the angle it spans
+// is sharp enough.
+
+package contour
+
+import (
+	"Backend/src/corner"
+	"Backend/src/imaging"
+	"image"
+	"image/color"
+	"math"
+)
+
+type Options struct {
+	Threshold  float64 // binarization threshold, 0 uses otsu
+	Foreground Foreground
+	MinLength  int     // contours with fewer points are ignored
+	SigmaHigh  float64 // coarse scale where corners are detected
+	SigmaLow   float64 // fine scale corners are tracked to
+	Curvature  float64 // minimum absolute curvature at the coarse scale
+	Ratio      float64 // how far a corner must exceed the mean curvature of its region of support
+	MaxAngle   float64 // corners spanning a wider angle (degrees) are rejected
+	Epsilon    float64 // douglas-peucker tolerance for the polygon approximation
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MinLength: 30,
+		SigmaHigh: 4,
+		SigmaLow:  1,
+		Curvature: 0.05,
+		Ratio:     1.5,
+		MaxAngle:  162,
+		Epsilon:   2,
+	}
+}
+
+// Corner is a curvature corner together with the contour it lies on and its position along it
+type Corner struct {
+	corner.Corner
+	Contour int `json:"contour"`
+	Index   int `json:"index"`
+}
+
+// smooth convolves the closed contour with a gaussian, wrapping around its ends
+func smooth(points []image.Point, sigma float64) ([]float64, []float64) {
+	n := len(points)
+	kernel := imaging.GaussianKernel(sigma)
+	r := len(kernel) / 2
+	xs := make([]float64, n)
+	ys := make([]float64, n)
+	for i := range points {
+		for k, w := range kernel {
+			p := points[((i+k-r)%n+n)%n]
+			xs[i] += w * float64(p.X)
+			ys[i] += w * float64(p.Y)
+		}
+	}
+	return xs, ys
+}
+
+// curvature returns the signed curvature of the contour smoothed at sigma
+func curvature(points []image.Point, sigma float64) []float64 {
+	xs, ys := smooth(points, sigma)
+	n := len(points)
+	k := make([]float64, n)
+	for i := range k {
+		prev, next := (i-1+n)%n, (i+1)%n
+		dx, dy := (xs[next]-xs[prev])/2, (ys[next]-ys[prev])/2
+		ddx, ddy := xs[next]-2*xs[i]+xs[prev], ys[next]-2*ys[i]+ys[prev]
+		den := math.Pow(dx*dx+dy*dy, 1.5)
+		if den > 0 {
+			k[i] = (dx*ddy - dy*ddx) / den
+		}
+	}
+	return k
+}
+
+// CurvatureCorners finds the corners of one closed contour, returning their indices along it
+func CurvatureCorners(points []image.Point, opts Options) []int {
+	n := len(points)
+	if n < 3 {
+		return nil
+	}
+	k := curvature(points, opts.SigmaHigh)
+	abs := make([]float64, n)
+	for i, v := range k {
+		abs[i] = math.Abs(v)
+	}
+	isMax := func(a []float64, i int) bool {
+		return a[i] > a[(i-1+n)%n] && a[i] >= a[(i+1)%n]
+	}
+	isMin := func(a []float64, i int) bool {
+		return a[i] <= a[(i-1+n)%n] && a[i] <= a[(i+1)%n]
+	}
+
+	candidates := make([]int, 0)
+	for i := range abs {
+		if !isMax(abs, i) || abs[i] < opts.Curvature {
+			continue
+		}
+		// region of support runs to the neighbouring curvature minima
+		lo, hi := (i-1+n)%n, (i+1)%n
+		for steps := 0; steps < n/2 && !isMin(abs, lo); steps++ {
+			lo = (lo - 1 + n) % n
+		}
+		for steps := 0; steps < n/2 && !isMin(abs, hi); steps++ {
+			hi = (hi + 1) % n
+		}
+		sum, count := 0.0, 0
+		for j := lo; ; j = (j + 1) % n {
+			sum += abs[j]
+			count++
+			if j == hi {
+				break
+			}
+		}
+		if abs[i] < opts.Ratio*sum/float64(count) {
+			continue
+		}
+		if angleAt(points, i, (i-lo+n)%n, (hi-i+n)%n) > opts.MaxAngle {
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+
+	// track every corner from the coarse scale down to the fine scale
+	for sigma := opts.SigmaHigh - 1; sigma >= opts.SigmaLow; sigma-- {
+		fine := curvature(points, sigma)
+		for c, i := range candidates {
+			best := i
+			for d := -2; d <= 2; d++ {
+				j := ((i+d)%n + n) % n
+				if math.Abs(fine[j]) > math.Abs(fine[best]) {
+					best = j
+				}
+			}
+			candidates[c] = best
+		}
+	}
+
+	// tracking can merge neighbouring corners
+	seen := make(map[int]bool)
+	out := make([]int, 0, len(candidates))
+	for _, i := range candidates {
+		if !seen[i] {
+			seen[i] = true
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// angleAt returns the angle in degrees at point i between the directions to the ends of its region
+// of support, capped at a few pixels either side
+func angleAt(points []image.Point, i, back, forward int) float64 {
+	n := len(points)
+	back = max(1, min(back, 10))
+	forward = max(1, min(forward, 10))
+	p := points[i]
+	a := points[(i-back+n)%n].Sub(p)
+	b := points[(i+forward)%n].Sub(p)
+	la := math.Hypot(float64(a.X), float64(a.Y))
+	lb := math.Hypot(float64(b.X), float64(b.Y))
+	if la == 0 || lb == 0 {
+		return 180
+	}
+	cos := float64(a.X*b.X+a.Y*b.Y) / (la * lb)
+	return math.Acos(math.Max(-1, math.Min(1, cos))) * 180 / math.Pi
+}
+
+// Detect extracts the contours of the thresholded image, their polygon approximation and the
+// curvature corners on each of them. Contours shorter than MinLength get no polygon or corners.
+func Detect(gray *imaging.Float, opts Options) ([]Contour, []Corner) {
+	contours := FindContours(Binarize(gray, opts.Threshold, opts.Foreground), gray.W, gray.H)
+	corners := make([]Corner, 0)
+	for i, c := range contours {
+		if len(c.Points) < opts.MinLength {
+			continue
+		}
+		contours[i].Polygon = ApproxPolygon(c.Points, opts.Epsilon)
+		k := curvature(c.Points, opts.SigmaLow)
+		for _, i := range CurvatureCorners(c.Points, opts) {
+			p := c.Points[i]
+			corners = append(corners, Corner{
+				Corner:  corner.Corner{X: float64(p.X), Y: float64(p.Y), Score: math.Abs(k[i])},
+				Contour: c.ID,
+				Index:   i,
+			})
+		}
+	}
+	return contours, corners
+}
+
+// Render draws the contours of at least minLength points in green and the corners in red over a
+// copy of img
+func Render(img image.Image, contours []Contour, corners []Corner, minLength int) *image.RGBA {
+	plain := make([]corner.Corner, len(corners))
+	for i, c := range corners {
+		plain[i] = c.Corner
+	}
+	rgba := corner.Draw(img, nil)
+	contourColor := color.RGBA{0, 255, 0, 255}
+	for _, c := range contours {
+		if len(c.Points) < minLength {
+			continue
+		}
+		for _, p := range c.Points {
+			rgba.Set(rgba.Rect.Min.X+p.X, rgba.Rect.Min.Y+p.Y, contourColor)
+		}
+	}
+	return corner.Draw(rgba, plain)
+}
+
+// Contours finds the contour corners of the image at inputPath and saves the image with the
+// contours in green and the corners in red to outputPath
+func Contours(inputPath, outputPath string, opts Options) ([]Contour, []Corner, error) {
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	contours, corners := Detect(imaging.ToFloat(img), opts)
+	if err := imaging.Save(Render(img, contours, corners, opts.MinLength), outputPath); err != nil {
+		return nil, nil, err
+	}
+	return contours, corners, nil
+}
@@ -0,0 +1,67 @@
+// douglas-peucker polygon approximation
+
+package contour
+
+import (
+	"image"
+	"math"
+)
+
+// distanceToSegment returns the distance from p to the segment a-b
+func distanceToSegment(p, a, b image.Point) float64 {
+	dx, dy := float64(b.X-a.X), float64(b.Y-a.Y)
+	px, py := float64(p.X-a.X), float64(p.Y-a.Y)
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return math.Hypot(px, py)
+	}
+	t := math.Max(0, math.Min(1, (px*dx+py*dy)/length))
+	return math.Hypot(px-t*dx, py-t*dy)
+}
+
+// simplify keeps the end points of points[lo:hi+1] and recursively the point farthest from the
+// chord between them while it is more than epsilon away
+func simplify(points []image.Point, lo, hi int, epsilon float64, keep []bool) {
+	far, farDist := -1, epsilon
+	for i := lo + 1; i < hi; i++ {
+		if d := distanceToSegment(points[i], points[lo], points[hi]); d > farDist {
+			far, farDist = i, d
+		}
+	}
+	if far < 0 {
+		return
+	}
+	keep[far] = true
+	simplify(points, lo, far, epsilon, keep)
+	simplify(points, far, hi, epsilon, keep)
+}
+
+// ApproxPolygon approximates a closed contour by a polygon whose vertices are contour points and
+// whose edges stay within epsilon of the contour. The contour is split at its first point and the
+// point farthest from it, so the result does not depend on where the border following started.
+func ApproxPolygon(points []image.Point, epsilon float64) []image.Point {
+	n := len(points)
+	if n < 3 {
+		return append([]image.Point(nil), points...)
+	}
+	far, farDist := 0, -1.0
+	for i, p := range points {
+		d := math.Hypot(float64(p.X-points[0].X), float64(p.Y-points[0].Y))
+		if d > farDist {
+			far, farDist = i, d
+		}
+	}
+	closed := append(append([]image.Point(nil), points...), points[0])
+	keep := make([]bool, n+1)
+	keep[0], keep[far] = true, true
+	simplify(closed, 0, far, epsilon, keep)
+	simplify(closed, far, n, epsilon, keep)
+
+	polygon := make([]image.Point, 0)
+	for i := 0; i < n; i++ {
+		if keep[i] {
+			polygon = append(polygon, points[i])
+		}
+	}
+	return polygon
+}
@@ -0,0 +1,85 @@
+// common corner type returned by the detectors
+
+package corner
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Corner is a detected interest point. Scale is the characteristic scale (sigma) for
+// scale adapted detectors and 0 for single scale ones. Angle is the dominant orientation in
+// degrees, measured like junction angles, and only meaningful when HasAngle is set, so an angle
+// of 0 stays apart from one that was never computed.
+type Corner struct {
+	X        float64   `json:"x"`
+	Y        float64   `json:"y"`
+	Score    float64   `json:"score"`
+	Scale    float64   `json:"scale,omitempty"`
+	Angle    float64   `json:"-"`
+	HasAngle bool      `json:"-"`
+	Junction *Junction `json:"junction,omitempty"`
+}
+
+// jsonCorner carries the angle of a Corner in json, where it is present exactly when computed
+type jsonCorner struct {
+	plainCorner
+	Angle *float64 `json:"angle,omitempty"`
+}
+
+type plainCorner Corner
+
+func (c Corner) MarshalJSON() ([]byte, error) {
+	out := jsonCorner{plainCorner: plainCorner(c)}
+	if c.HasAngle {
+		out.Angle = &c.Angle
+	}
+	return json.Marshal(out)
+}
+
+func (c *Corner) UnmarshalJSON(data []byte) error {
+	var in jsonCorner
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*c = Corner(in.plainCorner)
+	if in.Angle != nil {
+		c.Angle, c.HasAngle = *in.Angle, true
+	}
+	return nil
+}
+
+type JunctionType string
+
+const (
+	JunctionL         JunctionType = "L" // two edges meeting at an angle
+	JunctionT         JunctionType = "T" // an edge ending on a straight edge
+	JunctionY         JunctionType = "Y" // three edges, none collinear
+	JunctionX         JunctionType = "X" // four edges, two crossing lines
+	JunctionAmbiguous JunctionType = "ambiguous"
+)
+
+// Junction describes the edges leaving a corner. Angles are the edge directions in degrees in
+// [0, 360), measured from the x axis towards the y axis (clockwise on screen).
+type Junction struct {
+	Type   JunctionType `json:"type"`
+	Angles []float64    `json:"angles"`
+}
+
+// Draw copies img and marks every corner with a red pixel
+func Draw(img image.Image, corners []Corner) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(bounds)
+	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+
+	pointColor := color.RGBA{255, 0, 0, 255} // Red color
+	for _, c := range corners {
+		x, y := bounds.Min.X+int(c.X+0.5), bounds.Min.Y+int(c.Y+0.5)
+		if image.Pt(x, y).In(bounds) {
+			rgba.Set(x, y, pointColor)
+		}
+	}
+	return rgba
+}
@@ -0,0 +1,98 @@
+// patch export as images and as float arrays
+
+package descriptor
+
+import (
+	"Backend/src/imaging"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Image converts the patch to 8 bit, stretching its values to the full gray range
+func (p Patch) Image() *image.Gray {
+	f := imaging.NewFloat(p.Size, p.Size)
+	for i, v := range p.Data {
+		f.Pix[i] = float64(v)
+	}
+	return imaging.Normalize(f)
+}
+
+// Mosaic tiles the patch images in a grid with cols patches per row
+func Mosaic(patches []Patch, cols int) *image.Gray {
+	if len(patches) == 0 || cols < 1 {
+		return image.NewGray(image.Rect(0, 0, 0, 0))
+	}
+	size := patches[0].Size
+	rows := (len(patches) + cols - 1) / cols
+	out := image.NewGray(image.Rect(0, 0, cols*size, rows*size))
+	for k, p := range patches {
+		img := p.Image()
+		ox, oy := (k%cols)*size, (k/cols)*size
+		for y := 0; y < size; y++ {
+			copy(out.Pix[(oy+y)*out.Stride+ox:], img.Pix[y*img.Stride:y*img.Stride+size])
+		}
+	}
+	return out
+}
+
+// SaveImages writes every patch as dir/patch_0000.png and so on
+func SaveImages(patches []Patch, dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	for i, p := range patches {
+		if err := imaging.Save(p.Image(), filepath.Join(dir, fmt.Sprintf("patch_%04d.png", i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteNPY writes the patches as a float32 numpy array of shape (count, size, size), which loads
+// directly with numpy.load
+func WriteNPY(w io.Writer, patches []Patch) error {
+	size := 0
+	if len(patches) > 0 {
+		size = patches[0].Size
+	}
+	header := fmt.Sprintf("{'descr': '<f4', 'fortran_order': False, 'shape': (%d, %d, %d), }", len(patches), size, size)
+	// magic, version and header length take 10 bytes, the header is padded to a multiple of 64
+	pad := 64 - (10+len(header)+1)%64
+	header += strings.Repeat(" ", pad%64) + "\n"
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(bw, binary.LittleEndian, uint16(len(header)))
+	bw.WriteString(header)
+	buf := make([]byte, 4)
+	for _, p := range patches {
+		if p.Size != size {
+			return fmt.Errorf("patch of size %d in an array of size %d", p.Size, size)
+		}
+		for _, v := range p.Data {
+			binary.LittleEndian.PutUint32(buf, math.Float32bits(v))
+			bw.Write(buf)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteCSV writes one patch per line, the corner position and angle followed by the patch values
+func WriteCSV(w io.Writer, patches []Patch) error {
+	bw := bufio.NewWriter(w)
+	for _, p := range patches {
+		fmt.Fprintf(bw, "%g,%g,%g", p.Corner.X, p.Corner.Y, p.Corner.Angle)
+		for _, v := range p.Data {
+			fmt.Fprintf(bw, ",%g", v)
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
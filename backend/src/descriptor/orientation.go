@@ -0,0 +1,101 @@
+// dominant orientation of corners
+
+package descriptor
+
+import (
+	"Backend/src/corner"
+	"Backend/src/imaging"
+	"math"
+)
+
+type Method int
+
+const (
+	Centroid  Method = iota // intensity centroid of a disc, as used by orb
+	Histogram               // peak of the gradient orientation histogram, as used by sift
+)
+
+// radiusFor returns the support radius around a corner, used for both its orientation and its
+// patch: 6 sigma for corners with a scale, radius pixels for the others
+func radiusFor(c corner.Corner, radius float64) float64 {
+	if c.Scale > 0 {
+		return 6 * c.Scale
+	}
+	return radius
+}
+
+// CentroidAngle returns the direction in degrees from (x, y) to the intensity centroid of the disc
+// of the given radius around it
+func CentroidAngle(gray *imaging.Float, x, y, radius float64) float64 {
+	var m10, m01 float64
+	r := int(math.Ceil(radius))
+	for j := -r; j <= r; j++ {
+		for i := -r; i <= r; i++ {
+			if float64(i*i+j*j) > radius*radius {
+				continue
+			}
+			v := gray.Bilinear(x+float64(i), y+float64(j))
+			m10 += float64(i) * v
+			m01 += float64(j) * v
+		}
+	}
+	return normalizeDegrees(math.Atan2(m01, m10) * 180 / math.Pi)
+}
+
+// HistogramAngle returns the peak of a 36 bin histogram of gradient directions within radius of
+// (x, y), weighted by gradient magnitude and a gaussian window, interpolated between bins
+func HistogramAngle(dx, dy *imaging.Float, x, y, radius float64) float64 {
+	const bins = 36
+	var hist [bins]float64
+	sigma := radius / 2
+	r := int(math.Ceil(radius))
+	for j := -r; j <= r; j++ {
+		for i := -r; i <= r; i++ {
+			d2 := float64(i*i + j*j)
+			if d2 > radius*radius {
+				continue
+			}
+			gx, gy := dx.Bilinear(x+float64(i), y+float64(j)), dy.Bilinear(x+float64(i), y+float64(j))
+			w := math.Hypot(gx, gy) * math.Exp(-d2/(2*sigma*sigma))
+			angle := normalizeDegrees(math.Atan2(gy, gx) * 180 / math.Pi)
+			hist[int(angle/(360/bins))%bins] += w
+		}
+	}
+	best := 0
+	for b := range hist {
+		if hist[b] > hist[best] {
+			best = b
+		}
+	}
+	l, c, rr := hist[(best-1+bins)%bins], hist[best], hist[(best+1)%bins]
+	offset := 0.0
+	if den := l - 2*c + rr; den != 0 {
+		offset = 0.5 * (l - rr) / den
+	}
+	return normalizeDegrees((float64(best) + 0.5 + offset) * 360 / bins)
+}
+
+func normalizeDegrees(a float64) float64 {
+	a = math.Mod(a, 360)
+	if a < 0 {
+		a += 360
+	}
+	return a
+}
+
+// Orient sets the Angle and HasAngle of every corner in place
+func Orient(gray *imaging.Float, corners []corner.Corner, method Method, radius float64) {
+	var dx, dy *imaging.Float
+	if method == Histogram {
+		dx, dy = imaging.Sobel(imaging.Gaussian(gray, 1))
+	}
+	for i, c := range corners {
+		r := radiusFor(c, radius)
+		if method == Histogram {
+			corners[i].Angle = HistogramAngle(dx, dy, c.X, c.Y, r)
+		} else {
+			corners[i].Angle = CentroidAngle(gray, c.X, c.Y, r)
+		}
+		corners[i].HasAngle = true
+	}
+}
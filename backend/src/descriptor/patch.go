@@ -0,0 +1,120 @@
+// rotation aligned, normalized patches around corners for external learning pipelines
+
+package descriptor
+
+import (
+	"Backend/src/corner"
+	"Backend/src/imaging"
+	"math"
+)
+
+// Patch is a size x size sample of the image around a corner, rotated so the corner orientation
+// points along the x axis, stored row major
+type Patch struct {
+	Corner corner.Corner
+	Size   int
+	Data   []float32
+}
+
+type Normalization int
+
+const (
+	ZeroMeanUnitVariance Normalization = iota // robust to brightness and contrast changes
+	MinMax                                    // stretched to [0, 1]
+	Raw                                       // gray levels as sampled
+)
+
+type Options struct {
+	Size          int     // patch side in samples
+	Radius        float64 // half side of the patch in pixels for corners without a scale
+	Method        Method
+	Normalization Normalization
+}
+
+func DefaultOptions() Options {
+	return Options{Size: 32, Radius: 16, Method: Centroid, Normalization: ZeroMeanUnitVariance}
+}
+
+// Extract samples the patch of one corner, which must already have its Angle set. The patch
+// reaches radiusFor pixels on each side, the same support its orientation was measured over.
+func Extract(gray *imaging.Float, c corner.Corner, size int, radius float64, norm Normalization) Patch {
+	half := radiusFor(c, radius)
+	theta := c.Angle * math.Pi / 180
+	cos, sin := math.Cos(theta), math.Sin(theta)
+	step := 2 * half / float64(size)
+
+	// smooth before sampling when the patch is coarser than the pixels, to avoid aliasing. Only the
+	// area the rotated patch can reach is smoothed.
+	src, ox, oy := gray, c.X, c.Y
+	if step > 1 {
+		margin := half*math.Sqrt2 + 1
+		x0, y0 := math.Floor(c.X-margin), math.Floor(c.Y-margin)
+		src = imaging.Gaussian(crop(gray, int(x0), int(y0), int(math.Ceil(2*margin))+2), 0.5*step)
+		ox, oy = c.X-x0, c.Y-y0
+	}
+
+	p := Patch{Corner: c, Size: size, Data: make([]float32, size*size)}
+	for j := 0; j < size; j++ {
+		v := (float64(j)+0.5)*step - half
+		for i := 0; i < size; i++ {
+			u := (float64(i)+0.5)*step - half
+			p.Data[j*size+i] = float32(src.Bilinear(ox+u*cos-v*sin, oy+u*sin+v*cos))
+		}
+	}
+	normalize(p.Data, norm)
+	return p
+}
+
+// crop copies the side x side square starting at (x0, y0), replicating the image border
+func crop(gray *imaging.Float, x0, y0, side int) *imaging.Float {
+	out := imaging.NewFloat(side, side)
+	for j := 0; j < side; j++ {
+		for i := 0; i < side; i++ {
+			out.Set(i, j, gray.Clamped(x0+i, y0+j))
+		}
+	}
+	return out
+}
+
+func normalize(data []float32, norm Normalization) {
+	switch norm {
+	case ZeroMeanUnitVariance:
+		var mean, variance float64
+		for _, v := range data {
+			mean += float64(v)
+		}
+		mean /= float64(len(data))
+		for _, v := range data {
+			variance += (float64(v) - mean) * (float64(v) - mean)
+		}
+		std := math.Sqrt(variance / float64(len(data)))
+		if std < 1e-6 {
+			std = 1
+		}
+		for i, v := range data {
+			data[i] = float32((float64(v) - mean) / std)
+		}
+	case MinMax:
+		min, max := float32(math.Inf(1)), float32(math.Inf(-1))
+		for _, v := range data {
+			min, max = float32(math.Min(float64(min), float64(v))), float32(math.Max(float64(max), float64(v)))
+		}
+		scale := max - min
+		if scale == 0 {
+			scale = 1
+		}
+		for i, v := range data {
+			data[i] = (v - min) / scale
+		}
+	}
+}
+
+// Describe orients every corner in place and extracts its patch
+func Describe(gray *imaging.Float, corners []corner.Corner, opts Options) []Patch {
+	Orient(gray, corners, opts.Method, opts.Radius)
+	patches := make([]Patch, len(corners))
+	for i, c := range corners {
+		patches[i] = Extract(gray, c, opts.Size, opts.Radius, opts.Normalization)
+	}
+	return patches
+}
@@ -0,0 +1,188 @@
+// picks detector thresholds automatically so a run returns roughly a target number of corners
+
+package detector
+
+import (
+	"Backend/src/corner"
+	"Backend/src/imaging"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+var ErrInvalidTarget = errors.New("invalid target corner count")
+
+// AutoOptions configures AutoThreshold
+type AutoOptions struct {
+	Target int // number of corners wanted
+	TilesX int // tile columns, more than one tile in either direction selects a threshold per tile
+	TilesY int // tile rows
+}
+
+// DefaultAutoOptions returns a single global threshold aiming at 500 corners
+func DefaultAutoOptions() AutoOptions {
+	return AutoOptions{Target: 500, TilesX: 1, TilesY: 1}
+}
+
+// Selection records the thresholds AutoThreshold settled on
+type Selection struct {
+	Threshold float64     `json:"threshold"`       // global threshold, or the lowest tile threshold in tiled mode
+	Tiles     [][]float64 `json:"tiles,omitempty"` // per tile score thresholds, indexed [row][column]
+	Runs      int         `json:"runs"`            // number of detector runs the search needed
+}
+
+// permissive holds, per detector, a threshold low enough to let every plausible corner through
+var permissive = map[string]float64{
+	"fast":                20,
+	"harris":              0,
+	"shi-tomashi":         0,
+	"harris-laplace":      0,
+	"shi-tomashi-laplace": 0,
+}
+
+// AutoThreshold runs the configured detector with a threshold chosen to return opts.Target corners,
+// overriding any threshold in cfg.Params. The image is prepared once for all the runs.
+//
+// The harris style detectors are run once with a permissive threshold and the strongest corners
+// kept, which is exactly what rerunning at the score of the weakest kept corner would return, since
+// their distance filtering visits corners strongest first. For the laplace variants the reported
+// threshold is in absolute score units rather than the relative fraction their options take.
+// FAST thresholds intensity differences rather than its score, so its threshold is binary searched
+// for the highest value still yielding the target before the strongest corners are kept.
+//
+// With more than one tile every tile keeps its own share of the target, which adapts the threshold
+// to images whose contrast varies across the frame. Tile thresholds are always on the corner score.
+func AutoThreshold(img image.Image, cfg Config, opts AutoOptions) (*Result, Selection, error) {
+	name := cfg.Detector
+	low, ok := permissive[name]
+	if !ok {
+		return nil, Selection{}, fmt.Errorf("%w %q", ErrUnknown, name)
+	}
+	if opts.Target <= 0 {
+		return nil, Selection{}, fmt.Errorf("%w %d", ErrInvalidTarget, opts.Target)
+	}
+	if opts.TilesX < 1 {
+		opts.TilesX = 1
+	}
+	if opts.TilesY < 1 {
+		opts.TilesY = 1
+	}
+	tiled := opts.TilesX > 1 || opts.TilesY > 1
+	channels, cfg, err := prepare(img, cfg)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	w, h := channels[0].W, channels[0].H
+	result := &Result{Config: cfg, Width: w, Height: h, BitDepth: imaging.BitDepth(img)}
+
+	run := func(threshold float64) ([]corner.Corner, error) {
+		p := Params{}
+		for k, v := range cfg.Params {
+			p[k] = v
+		}
+		p["threshold"] = threshold
+		if name == "harris-laplace" || name == "shi-tomashi-laplace" {
+			p["max_corners"] = 0
+		}
+		return detectChannels(name, channels, p, nil)
+	}
+
+	if name == "fast" && !tiled {
+		corners, sel, err := searchFast(run, opts.Target)
+		if err != nil {
+			return nil, sel, err
+		}
+		result.Corners = corners
+		return result, sel, nil
+	}
+
+	corners, err := run(low)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	sortByScore(corners)
+	if !tiled {
+		result.Corners = strongest(corners, opts.Target)
+		sel := Selection{Runs: 1}
+		if len(result.Corners) > 0 {
+			sel.Threshold = result.Corners[len(result.Corners)-1].Score
+		}
+		return result, sel, nil
+	}
+	var tiles [][]float64
+	result.Corners, tiles = perTile(corners, w, h, opts)
+	sel := Selection{Threshold: math.Inf(1), Tiles: tiles, Runs: 1}
+	for _, row := range tiles {
+		for _, t := range row {
+			sel.Threshold = math.Min(sel.Threshold, t)
+		}
+	}
+	return result, sel, nil
+}
+
+// searchFast binary searches the integer FAST threshold for the highest one giving at least target
+// corners, falling back to the lowest threshold when even that gives too few
+func searchFast(run func(float64) ([]corner.Corner, error), target int) ([]corner.Corner, Selection, error) {
+	sel := Selection{}
+	lo, hi := 1, 254
+	var best []corner.Corner
+	bestThreshold := 0
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		corners, err := run(float64(mid))
+		sel.Runs++
+		if err != nil {
+			return nil, sel, err
+		}
+		if len(corners) >= target {
+			best, bestThreshold = corners, mid
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	if best == nil {
+		corners, err := run(1)
+		sel.Runs++
+		if err != nil {
+			return nil, sel, err
+		}
+		best, bestThreshold = corners, 1
+	}
+	sortByScore(best)
+	sel.Threshold = float64(bestThreshold)
+	return strongest(best, target), sel, nil
+}
+
+// strongest returns the first n corners of a list sorted by score
+func strongest(corners []corner.Corner, n int) []corner.Corner {
+	if len(corners) > n {
+		return corners[:n]
+	}
+	return corners
+}
+
+// perTile keeps the strongest share of the target in each tile of a score sorted list and reports
+// the score of the weakest corner kept per tile, or zero for a tile without corners
+func perTile(corners []corner.Corner, w, h int, opts AutoOptions) ([]corner.Corner, [][]float64) {
+	share := int(math.Ceil(float64(opts.Target) / float64(opts.TilesX*opts.TilesY)))
+	counts := make([][]int, opts.TilesY)
+	tiles := make([][]float64, opts.TilesY)
+	for i := range tiles {
+		counts[i] = make([]int, opts.TilesX)
+		tiles[i] = make([]float64, opts.TilesX)
+	}
+	kept := make([]corner.Corner, 0, opts.Target)
+	for _, c := range corners {
+		tx := min(max(int(c.X)*opts.TilesX/max(w, 1), 0), opts.TilesX-1)
+		ty := min(max(int(c.Y)*opts.TilesY/max(h, 1), 0), opts.TilesY-1)
+		if counts[ty][tx] == share {
+			continue
+		}
+		counts[ty][tx]++
+		tiles[ty][tx] = c.Score
+		kept = append(kept, c)
+	}
+	return kept, tiles
+}
@@ -0,0 +1,72 @@
+// intermediate maps of a detection, for seeing why corners appear where they do
+
+package detector
+
+import (
+	"Backend/src/imaging"
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+)
+
+// Debug is Detect that also collects the intermediate maps: the gray input, the preprocessed
+// channels and whatever the detector reports, such as gradients, their products and the response
+func Debug(img image.Image, cfg Config) (*Result, *imaging.Maps, error) {
+	maps := imaging.NewMaps()
+	result, err := detect(img, cfg, maps)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, maps, nil
+}
+
+var ErrNoResponse = errors.New("no response map")
+
+// Response picks the cornerness map of the named detector out of the maps Debug collected. For the
+// laplace variants it is the strongest response over all scales.
+func Response(name string, maps *imaging.Maps) (*imaging.Float, error) {
+	key := map[string]string{"fast": "fast_score", "harris": "harris_r", "shi-tomashi": "min_eigen"}[name]
+	if f, ok := maps.Images[key]; ok {
+		return f, nil
+	}
+	var best *imaging.Float
+	for n := 0; ; n++ {
+		f, ok := maps.Images[fmt.Sprintf("response_s%d", n)]
+		if !ok {
+			break
+		}
+		if best == nil {
+			best = f.Clone()
+			continue
+		}
+		for i, v := range f.Pix {
+			best.Pix[i] = max(best.Pix[i], v)
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w for %q", ErrNoResponse, name)
+	}
+	return best, nil
+}
+
+// WriteBundle writes a zip archive holding the result as result.json and every map as a normalized
+// png or a raw float tiff, depending on format
+func WriteBundle(w io.Writer, result *Result, maps *imaging.Maps, format string) error {
+	zw := zip.NewWriter(w)
+	rw, err := zw.Create("result.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(rw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		return err
+	}
+	if err := maps.WriteZip(zw, format); err != nil {
+		return err
+	}
+	return zw.Close()
+}
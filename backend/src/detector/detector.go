@@ -0,0 +1,247 @@
+// runs any of the corner detectors by name on a decoded image
+
+package detector
+
+import (
+	"Backend/src/corner"
+	"Backend/src/fast"
+	"Backend/src/harris"
+	"Backend/src/harrisLaplace"
+	"Backend/src/imaging"
+	"Backend/src/preprocess"
+	"Backend/src/shiTomashi"
+	"errors"
+	"fmt"
+	"image"
+	"sort"
+)
+
+var (
+	ErrUnknown          = errors.New("unknown detector")
+	ErrUnknownParam     = errors.New("unknown detector parameter")
+	ErrColorUnsupported = errors.New("detector does not support color")
+)
+
+// Names lists the detectors Run accepts
+var Names = []string{"fast", "harris", "shi-tomashi", "harris-laplace", "shi-tomashi-laplace"}
+
+// Params overrides detector options by name, for example {"threshold": 50, "min_dist": 5}.
+// Options that are not set keep their defaults. Intensity thresholds are on the 0..255 scale every
+// input is converted to, so they need no rescaling for 16 bit or float images.
+type Params map[string]float64
+
+// set copies the recognised params into the option fields, failing on any other name
+func (p Params) set(name string, fields map[string]*float64) error {
+	for key, value := range p {
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("%w %q for %s", ErrUnknownParam, key, name)
+		}
+		*field = value
+	}
+	return nil
+}
+
+// Config selects a detector together with everything that shapes its input and options. A nil
+// Preprocess means the detector default and an empty Color means gray.
+type Config struct {
+	Detector   string              `json:"detector"`
+	Params     Params              `json:"params,omitempty"`
+	Preprocess preprocess.Pipeline `json:"preprocess"`
+	Color      imaging.ColorSpace  `json:"color,omitempty"`
+}
+
+// Result is a detection together with the resolved config needed to reproduce it. ConfigHash
+// identifies the pipeline spec that produced it, when one did.
+type Result struct {
+	Config
+	ConfigHash string          `json:"config_hash,omitempty"`
+	Width      int             `json:"width"`
+	Height     int             `json:"height"`
+	BitDepth   int             `json:"bit_depth"` // bits per channel of the decoded input
+	Corners    []corner.Corner `json:"corners"`
+}
+
+// DefaultPreprocess returns the preprocessing a detector's http route has always applied: a 3x3
+// median for fast, harris and shi-tomashi and nothing for the laplace variants, which smooth anyway
+func DefaultPreprocess(name string) preprocess.Pipeline {
+	switch name {
+	case "fast", "harris", "shi-tomashi":
+		return preprocess.MustParse("median:3")
+	}
+	return preprocess.MustParse("none")
+}
+
+// paramNames lists the params every detector accepts
+var paramNames = map[string][]string{
+	"fast":                {"threshold"},
+	"harris":              {"threshold", "k", "window", "min_dist"},
+	"shi-tomashi":         {"threshold", "window", "min_dist"},
+	"harris-laplace":      {"threshold", "k", "sigma0", "step", "scales", "diff_ratio", "max_corners"},
+	"shi-tomashi-laplace": {"threshold", "k", "sigma0", "step", "scales", "diff_ratio", "max_corners"},
+}
+
+// DefaultParams returns the value every param of the named detector takes when it is not set, and
+// no params for an unknown detector
+func DefaultParams(name string) Params {
+	switch name {
+	case "fast":
+		return Params{"threshold": float64(fast.DefaultOptions().Threshold)}
+	case "harris":
+		o := harris.DefaultOptions()
+		return Params{"threshold": o.Threshold, "k": o.K, "window": float64(o.Window), "min_dist": o.MinDist}
+	case "shi-tomashi":
+		o := shiTomashi.DefaultOptions()
+		return Params{"threshold": o.Threshold, "window": float64(o.Window), "min_dist": o.MinDist}
+	case "harris-laplace", "shi-tomashi-laplace":
+		o := harrisLaplace.DefaultOptions()
+		return Params{
+			"threshold": o.Threshold, "k": o.K, "sigma0": o.Sigma0, "step": o.Step,
+			"scales": float64(o.Scales), "diff_ratio": o.DiffRatio, "max_corners": float64(o.MaxCorners),
+		}
+	}
+	return Params{}
+}
+
+// Validate reports the errors Detect would return for cfg on any image: an unknown detector or
+// param, or a color space the detector does not support
+func Validate(cfg Config) error {
+	names, ok := paramNames[cfg.Detector]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknown, cfg.Detector)
+	}
+	for key := range cfg.Params {
+		if !contains(names, key) {
+			return fmt.Errorf("%w %q for %s", ErrUnknownParam, key, cfg.Detector)
+		}
+	}
+	if cfg.Color != "" && cfg.Color != imaging.Gray && cfg.Detector != "harris" && cfg.Detector != "shi-tomashi" {
+		return fmt.Errorf("%w: %s on %s", ErrColorUnsupported, cfg.Detector, cfg.Color)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Run detects corners with the named detector, its default preprocessing and options, and any
+// params overrides
+func Run(name string, img image.Image, params Params) ([]corner.Corner, error) {
+	result, err := Detect(img, Config{Detector: name, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	return result.Corners, nil
+}
+
+// Detect splits a copy of img into the configured color channels, preprocesses each and runs the
+// configured detector on them
+func Detect(img image.Image, cfg Config) (*Result, error) {
+	return detect(img, cfg, nil)
+}
+
+func detect(img image.Image, cfg Config, maps *imaging.Maps) (*Result, error) {
+	channels, cfg, err := prepare(img, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if maps.Enabled() {
+		maps.Add("gray", imaging.ToFloat(img))
+		for i, ch := range channels {
+			name := "filtered"
+			if len(channels) > 1 {
+				name = fmt.Sprintf("filtered_%d", i)
+			}
+			maps.Add(name, ch)
+		}
+	}
+	corners, err := detectChannels(cfg.Detector, channels, cfg.Params, maps)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Config: cfg, Width: channels[0].W, Height: channels[0].H, BitDepth: imaging.BitDepth(img), Corners: corners}, nil
+}
+
+// prepare resolves the config defaults and returns the preprocessed channels of img. Only harris and
+// shi-tomashi sum structure tensors over channels, so the other detectors accept gray only.
+func prepare(img image.Image, cfg Config) ([]*imaging.Float, Config, error) {
+	if cfg.Preprocess == nil {
+		cfg.Preprocess = DefaultPreprocess(cfg.Detector)
+	}
+	if cfg.Color == "" {
+		cfg.Color = imaging.Gray
+	}
+	if cfg.Color != imaging.Gray && cfg.Detector != "harris" && cfg.Detector != "shi-tomashi" {
+		return nil, cfg, fmt.Errorf("%w: %s on %s", ErrColorUnsupported, cfg.Detector, cfg.Color)
+	}
+	channels := imaging.Channels(img, cfg.Color)
+	for i, ch := range channels {
+		channels[i] = cfg.Preprocess.Apply(ch)
+	}
+	return channels, cfg, nil
+}
+
+// detectChannels runs the named detector on already preprocessed channels, reporting its
+// intermediate maps to maps when that is not nil
+func detectChannels(name string, channels []*imaging.Float, params Params, maps *imaging.Maps) ([]corner.Corner, error) {
+	gray := channels[0]
+	switch name {
+	case "fast":
+		opts := fast.DefaultOptions()
+		opts.Maps = maps
+		threshold := float64(opts.Threshold)
+		if err := params.set(name, map[string]*float64{"threshold": &threshold}); err != nil {
+			return nil, err
+		}
+		opts.Threshold = int(threshold)
+		return fast.DetectFloat(gray, opts), nil
+	case "harris":
+		opts := harris.DefaultOptions()
+		opts.Maps = maps
+		window := float64(opts.Window)
+		if err := params.set(name, map[string]*float64{"threshold": &opts.Threshold, "k": &opts.K, "window": &window, "min_dist": &opts.MinDist}); err != nil {
+			return nil, err
+		}
+		opts.Window = int(window)
+		return harris.DetectChannels(channels, opts), nil
+	case "shi-tomashi":
+		opts := shiTomashi.DefaultOptions()
+		opts.Maps = maps
+		window := float64(opts.Window)
+		if err := params.set(name, map[string]*float64{"threshold": &opts.Threshold, "window": &window, "min_dist": &opts.MinDist}); err != nil {
+			return nil, err
+		}
+		opts.Window = int(window)
+		return shiTomashi.DetectChannels(channels, opts), nil
+	case "harris-laplace", "shi-tomashi-laplace":
+		opts := harrisLaplace.DefaultOptions()
+		opts.Maps = maps
+		if name == "shi-tomashi-laplace" {
+			opts.Measure = harrisLaplace.ShiTomasi
+		}
+		scales, maxCorners := float64(opts.Scales), float64(opts.MaxCorners)
+		err := params.set(name, map[string]*float64{
+			"threshold": &opts.Threshold, "k": &opts.K, "sigma0": &opts.Sigma0, "step": &opts.Step,
+			"scales": &scales, "diff_ratio": &opts.DiffRatio, "max_corners": &maxCorners,
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts.Scales, opts.MaxCorners = int(scales), int(maxCorners)
+		return harrisLaplace.Detect(gray, opts), nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknown, name)
+}
+
+// sortByScore orders corners strongest first
+func sortByScore(corners []corner.Corner) {
+	sort.SliceStable(corners, func(i, j int) bool {
+		return corners[i].Score > corners[j].Score
+	})
+}
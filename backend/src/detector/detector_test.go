@@ -0,0 +1,83 @@
+package detector
+
+import (
+	"Backend/src/corner"
+	"Backend/src/imaging"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// square draws a 40x40 square of inside color on a 100x100 background of outside color
+func square(outside, inside color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			img.Set(x, y, outside)
+			if x >= 30 && x < 70 && y >= 30 && y < 70 {
+				img.Set(x, y, inside)
+			}
+		}
+	}
+	return img
+}
+
+func TestDetectIsoluminantSquare(t *testing.T) {
+	// 0.299*157 = 0.587*79 + 0.114*5, so the green square has the gray level of the red background
+	img := square(color.NRGBA{R: 157, A: 255}, color.NRGBA{G: 79, B: 5, A: 255})
+	if gray := imaging.ToFloat(img); gray.At(10, 10) != gray.At(50, 50) {
+		t.Fatalf("square is not isoluminant: gray %v on %v", gray.At(50, 50), gray.At(10, 10))
+	}
+	// the corners each detector finds on the same square drawn in black and white
+	contrast := square(color.Gray{Y: 30}, color.Gray{Y: 220})
+	const tolerance = 1
+	tests := []struct {
+		detector string
+		color    imaging.ColorSpace
+		corners  int
+	}{
+		{"harris", imaging.Gray, 0},
+		{"shi-tomashi", imaging.Gray, 0},
+		{"harris", imaging.RGB, 4},
+		{"shi-tomashi", imaging.RGB, 4},
+		{"harris", imaging.Lab, 4},
+		{"shi-tomashi", imaging.Lab, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.detector+" on "+string(tt.color), func(t *testing.T) {
+			result, err := Detect(img, Config{Detector: tt.detector, Color: tt.color})
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			if len(result.Corners) != tt.corners {
+				t.Fatalf("found %d corners %v, want %d", len(result.Corners), result.Corners, tt.corners)
+			}
+			if tt.corners == 0 {
+				return
+			}
+			want, err := Run(tt.detector, contrast, nil)
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if len(want) != 4 {
+				t.Fatalf("found %d corners on the black and white square, want 4", len(want))
+			}
+			for _, w := range want {
+				if !near(result.Corners, w, tolerance) {
+					t.Errorf("no corner within %g px of %.0f,%.0f in %v", float64(tolerance), w.X, w.Y, result.Corners)
+				}
+			}
+		})
+	}
+}
+
+// near reports whether any of corners lies within tolerance of c
+func near(corners []corner.Corner, c corner.Corner, tolerance float64) bool {
+	for _, other := range corners {
+		if math.Hypot(other.X-c.X, other.Y-c.Y) <= tolerance {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+// post-processing that spreads corners evenly over the image
+//
+// Minimum distance filtering keeps every strong corner, so textured areas end up crowded while
+// smooth areas get none. These strategies select a target number of corners that are both strong
+// and spatially uniform, and work on the output of any detector.
+
+package distribution
+
+import (
+	"Backend/src/corner"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+var ErrUnknown = errors.New("unknown distribution strategy")
+
+// Strategies lists the names accepted by Apply
+var Strategies = []string{"anms", "ssc", "grid"}
+
+// Apply selects about count corners of a width x height image with the named strategy
+func Apply(strategy string, corners []corner.Corner, width, height, count int) ([]corner.Corner, error) {
+	switch strategy {
+	case "anms":
+		return ANMS(corners, count, 0.9), nil
+	case "ssc":
+		return SSC(corners, width, height, count, 0.1), nil
+	case "grid":
+		cells := int(math.Max(1, math.Round(math.Sqrt(float64(count)/4))))
+		return Grid(corners, width, height, cells, cells, count), nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknown, strategy)
+}
+
+func byScore(corners []corner.Corner) []corner.Corner {
+	sorted := append([]corner.Corner(nil), corners...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Score > sorted[j].Score
+	})
+	return sorted
+}
+
+// ANMS implements adaptive non-maximal suppression (brown, szeliski and winder 2005). Every corner
+// gets the distance to the nearest corner that is clearly stronger, score*robust > its own, and the
+// count corners with the largest suppression radius are returned.
+func ANMS(corners []corner.Corner, count int, robust float64) []corner.Corner {
+	sorted := byScore(corners)
+	if count >= len(sorted) {
+		return sorted
+	}
+	radius := make([]float64, len(sorted))
+	for i, c := range sorted {
+		best := math.Inf(1)
+		// only earlier corners can be stronger
+		for j := 0; j < i; j++ {
+			if sorted[j].Score*robust <= c.Score {
+				continue
+			}
+			dx, dy := sorted[j].X-c.X, sorted[j].Y-c.Y
+			if d := dx*dx + dy*dy; d < best {
+				best = d
+			}
+		}
+		radius[i] = best
+	}
+	order := make([]int, len(sorted))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return radius[order[a]] > radius[order[b]]
+	})
+	out := make([]corner.Corner, count)
+	for k := range out {
+		out[k] = sorted[order[k]]
+	}
+	return out
+}
+
+// cover greedily keeps corners in score order whose grid cell is not covered yet, covering the
+// cells within radius of every kept corner
+func cover(sorted []corner.Corner, width, height int, radius float64) []corner.Corner {
+	cell := math.Max(radius/math.Sqrt2, 1)
+	cols := int(float64(width)/cell) + 1
+	rows := int(float64(height)/cell) + 1
+	covered := make([]bool, cols*rows)
+	reach := int(math.Floor(radius / cell))
+	kept := make([]corner.Corner, 0)
+	for _, c := range sorted {
+		cx, cy := int(c.X/cell), int(c.Y/cell)
+		if cx < 0 || cy < 0 || cx >= cols || cy >= rows || covered[cy*cols+cx] {
+			continue
+		}
+		kept = append(kept, c)
+		for y := max(0, cy-reach); y <= min(rows-1, cy+reach); y++ {
+			for x := max(0, cx-reach); x <= min(cols-1, cx+reach); x++ {
+				covered[y*cols+x] = true
+			}
+		}
+	}
+	return kept
+}
+
+// SSC implements suppression via square covering (bailo et al. 2018). A binary search over the
+// suppression radius finds a covering that keeps count corners within tolerance (a fraction of
+// count); the result is trimmed to count.
+func SSC(corners []corner.Corner, width, height, count int, tolerance float64) []corner.Corner {
+	sorted := byScore(corners)
+	if count >= len(sorted) {
+		return sorted
+	}
+	// initial bounds from the reference implementation of the paper
+	n, k := float64(len(sorted)), float64(count)
+	w, h := float64(width), float64(height)
+	high := math.Max(w, h)
+	if count > 1 {
+		exp1 := h + w + 2*k
+		exp3 := math.Sqrt(4*w + 4*k + 4*h*k + h*h + w*w - 2*h*w + 4*h*w*k)
+		high = math.Max(-math.Round((exp1+exp3)/(k-1)), -math.Round((exp1-exp3)/(k-1)))
+	}
+	low := math.Floor(math.Sqrt(n / k))
+
+	lo, hi := int(low), int(high)
+	margin := int(math.Round(k * tolerance))
+	var best []corner.Corner
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		kept := cover(sorted, width, height, float64(max(mid, 1)))
+		if best == nil || math.Abs(float64(len(kept)-count)) < math.Abs(float64(len(best)-count)) {
+			best = kept
+		}
+		if len(kept) >= count-margin && len(kept) <= count+margin {
+			break
+		}
+		if len(kept) < count {
+			hi = mid - 1
+		} else {
+			lo = mid + 1
+		}
+	}
+	if len(best) > count {
+		best = best[:count]
+	}
+	return best
+}
+
+// Grid buckets corners into a rows x cols grid and keeps the strongest count/(rows*cols) corners in
+// every cell. Cells with fewer corners leave quota unused, which goes to the strongest remaining
+// corners anywhere so the target count is still met when possible.
+func Grid(corners []corner.Corner, width, height, rows, cols, count int) []corner.Corner {
+	sorted := byScore(corners)
+	if count >= len(sorted) {
+		return sorted
+	}
+	quota := (count + rows*cols - 1) / (rows * cols)
+	used := make([]int, rows*cols)
+	taken := make([]bool, len(sorted))
+	out := make([]corner.Corner, 0, count)
+	for i, c := range sorted {
+		cx := min(cols-1, max(0, int(c.X*float64(cols)/float64(width))))
+		cy := min(rows-1, max(0, int(c.Y*float64(rows)/float64(height))))
+		if used[cy*cols+cx] < quota {
+			used[cy*cols+cx]++
+			taken[i] = true
+			out = append(out, c)
+		}
+	}
+	for i, c := range sorted {
+		if len(out) >= count {
+			break
+		}
+		if !taken[i] {
+			out = append(out, c)
+		}
+	}
+	out = byScore(out)
+	if len(out) > count {
+		out = out[:count]
+	}
+	return out
+}
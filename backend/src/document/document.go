@@ -0,0 +1,269 @@
+// document and whiteboard corner detection with perspective rectification
+//
+// The page is found on a downscaled copy as the largest bright region after otsu thresholding, and
+// the quadrilateral of largest area inscribed in its convex hull gives a first guess of the four
+// sides. Each side is then refitted at full resolution to the strong edge pixels along it, with
+// gradients roughly perpendicular to the side, and the corners are the intersections of the fitted
+// lines refined to sub-pixel accuracy.
+
+package document
+
+import (
+	"Backend/src/chessboard"
+	"Backend/src/geometry"
+	"Backend/src/imaging"
+	"errors"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+)
+
+var ErrNoDocument = errors.New("no document outline found")
+
+// Quad holds the page corners ordered clockwise from the top left
+type Quad struct {
+	TopLeft     geometry.Point `json:"top_left"`
+	TopRight    geometry.Point `json:"top_right"`
+	BottomRight geometry.Point `json:"bottom_right"`
+	BottomLeft  geometry.Point `json:"bottom_left"`
+}
+
+func (q Quad) Points() [4]geometry.Point {
+	return [4]geometry.Point{q.TopLeft, q.TopRight, q.BottomRight, q.BottomLeft}
+}
+
+// Common page aspect ratios, width over height in portrait orientation
+const (
+	AspectA4     = 1 / math.Sqrt2
+	AspectLetter = 8.5 / 11
+)
+
+// ParseAspect reads a page aspect ratio given as a known paper name or as width/height. "auto" and ""
+// give 0, which lets Rectify estimate it.
+func ParseAspect(value string) (float64, error) {
+	switch value {
+	case "", "auto":
+		return 0, nil
+	case "a4":
+		return AspectA4, nil
+	case "letter":
+		return AspectLetter, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// workingSize is the longest side of the downscaled image used to locate the page
+const workingSize = 512
+
+// Detect finds the four corners of the dominant bright quadrilateral in gray
+func Detect(gray *imaging.Float) (Quad, error) {
+	factor := int(math.Ceil(float64(max(gray.W, gray.H)) / workingSize))
+	small := imaging.Gaussian(imaging.Downsample(gray, factor), 1.5)
+
+	region := largestRegion(small, imaging.Otsu(small))
+	if len(region) < small.W*small.H/50 {
+		return Quad{}, ErrNoDocument
+	}
+	hull := convexHull(region)
+	if len(hull) < 4 {
+		return Quad{}, ErrNoDocument
+	}
+	guess := largestQuad(hull)
+
+	// back to full resolution, the downscaled pixel centre sits at factor*(x+0.5)-0.5
+	for i := range guess {
+		guess[i] = geometry.Point{X: float64(factor)*(guess[i].X+0.5) - 0.5, Y: float64(factor)*(guess[i].Y+0.5) - 0.5}
+	}
+
+	var lines [4]line
+	for i := range guess {
+		a, b := guess[i], guess[(i+1)%4]
+		lines[i] = fitSide(gray, a, b, 2*float64(factor)+2)
+	}
+	var corners [4]geometry.Point
+	dx, dy := imaging.Derivatives(imaging.Gaussian(gray, 1))
+	for i := range corners {
+		p, ok := lines[(i+3)%4].intersect(lines[i])
+		if !ok {
+			p = guess[i]
+		}
+		corners[i] = chessboard.RefineCorner(dx, dy, p, 4)
+	}
+	return order(corners), nil
+}
+
+// largestRegion returns the pixels of the largest 4-connected component brighter than threshold
+func largestRegion(f *imaging.Float, threshold float64) []geometry.Point {
+	labels := make([]int, len(f.Pix))
+	best := make([]geometry.Point, 0)
+	label := 0
+	stack := make([]int, 0)
+	for start, v := range f.Pix {
+		if v <= threshold || labels[start] != 0 {
+			continue
+		}
+		label++
+		region := make([]geometry.Point, 0)
+		stack = append(stack[:0], start)
+		labels[start] = label
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%f.W, i/f.W
+			region = append(region, geometry.Point{X: float64(x), Y: float64(y)})
+			for _, n := range [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				if n[0] < 0 || n[1] < 0 || n[0] >= f.W || n[1] >= f.H {
+					continue
+				}
+				j := n[1]*f.W + n[0]
+				if labels[j] == 0 && f.Pix[j] > threshold {
+					labels[j] = label
+					stack = append(stack, j)
+				}
+			}
+		}
+		if len(region) > len(best) {
+			best = region
+		}
+	}
+	return best
+}
+
+// convexHull returns the hull of points in counter clockwise order (clockwise on screen) using
+// the monotone chain algorithm
+func convexHull(points []geometry.Point) []geometry.Point {
+	pts := append([]geometry.Point(nil), points...)
+	sort.Slice(pts, func(i, j int) bool {
+		if pts[i].X != pts[j].X {
+			return pts[i].X < pts[j].X
+		}
+		return pts[i].Y < pts[j].Y
+	})
+	hull := make([]geometry.Point, 0, 2*len(pts))
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, p := range pts {
+			for len(hull) >= start+2 && hull[len(hull)-1].Sub(hull[len(hull)-2]).Cross(p.Sub(hull[len(hull)-2])) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		hull = hull[:len(hull)-1]
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	return hull
+}
+
+func triangleArea(a, b, c geometry.Point) float64 {
+	return math.Abs(b.Sub(a).Cross(c.Sub(a))) / 2
+}
+
+// largestQuad picks the four hull vertices spanning the largest area. For each diagonal the best
+// vertex on either side is independent, which keeps the search cubic in the hull size.
+func largestQuad(hull []geometry.Point) [4]geometry.Point {
+	const maxVertices = 120
+	if len(hull) > maxVertices {
+		sampled := make([]geometry.Point, maxVertices)
+		for i := range sampled {
+			sampled[i] = hull[i*len(hull)/maxVertices]
+		}
+		hull = sampled
+	}
+	n := len(hull)
+	var best [4]geometry.Point
+	bestArea := -1.0
+	for i := 0; i < n; i++ {
+		for k := i + 2; k < n; k++ {
+			bestJ, areaJ := -1, 0.0
+			for j := i + 1; j < k; j++ {
+				if a := triangleArea(hull[i], hull[j], hull[k]); a > areaJ {
+					bestJ, areaJ = j, a
+				}
+			}
+			bestL, areaL := -1, 0.0
+			for l := k + 1; l < n+i; l++ {
+				if a := triangleArea(hull[k], hull[l%n], hull[i]); a > areaL {
+					bestL, areaL = l%n, a
+				}
+			}
+			if bestJ >= 0 && bestL >= 0 && areaJ+areaL > bestArea {
+				bestArea = areaJ + areaL
+				best = [4]geometry.Point{hull[i], hull[bestJ], hull[k], hull[bestL]}
+			}
+		}
+	}
+	return best
+}
+
+// order sorts corners around their centroid and rotates them to start at the top left
+func order(corners [4]geometry.Point) Quad {
+	var centre geometry.Point
+	for _, c := range corners {
+		centre = centre.Add(c.Scale(0.25))
+	}
+	pts := corners[:]
+	sort.Slice(pts, func(i, j int) bool {
+		a, b := pts[i].Sub(centre), pts[j].Sub(centre)
+		return math.Atan2(a.Y, a.X) < math.Atan2(b.Y, b.X)
+	})
+	// atan2 with y pointing down sorts clockwise on screen, start from the smallest x + y
+	start := 0
+	for i, p := range pts {
+		if p.X+p.Y < pts[start].X+pts[start].Y {
+			start = i
+		}
+	}
+	return Quad{pts[start], pts[(start+1)%4], pts[(start+2)%4], pts[(start+3)%4]}
+}
+
+// Rectify warps the quad of img to a fronto-parallel image. aspect is width over height, 0 estimates it
+// from the side lengths of the quad. The output height is the longest vertical side of the quad.
+func Rectify(img image.Image, q Quad, aspect float64) (*image.RGBA, error) {
+	left := q.TopLeft.Dist(q.BottomLeft)
+	right := q.TopRight.Dist(q.BottomRight)
+	top := q.TopLeft.Dist(q.TopRight)
+	bottom := q.BottomLeft.Dist(q.BottomRight)
+	height := math.Max(left, right)
+	if aspect <= 0 {
+		aspect = (top + bottom) / (left + right)
+	}
+	width := height * aspect
+	w, h := int(math.Round(width)), int(math.Round(height))
+	if w < 1 || h < 1 {
+		return nil, ErrNoDocument
+	}
+
+	target := []geometry.Point{{X: 0, Y: 0}, {X: width - 1, Y: 0}, {X: width - 1, Y: height - 1}, {X: 0, Y: height - 1}}
+	corners := q.Points()
+	hom, err := geometry.EstimateHomography(target, corners[:])
+	if err != nil {
+		return nil, err
+	}
+	return imaging.Warp(img, w, h, func(x, y float64) (float64, float64) {
+		p := hom.Apply(geometry.Point{X: x, Y: y})
+		return p.X, p.Y
+	}), nil
+}
+
+// Document finds the page in the image at inputPath and saves the rectified page to outputPath
+func Document(inputPath, outputPath string, aspect float64) (Quad, error) {
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		return Quad{}, err
+	}
+	quad, err := Detect(imaging.ToFloat(img))
+	if err != nil {
+		return Quad{}, err
+	}
+	rectified, err := Rectify(img, quad, aspect)
+	if err != nil {
+		return Quad{}, err
+	}
+	if err := imaging.Save(rectified, outputPath); err != nil {
+		return Quad{}, err
+	}
+	return quad, nil
+}
@@ -0,0 +1,100 @@
+// total least squares line fitting to the edge pixels along a page side
+
+package document
+
+import (
+	"Backend/src/geometry"
+	"Backend/src/imaging"
+	"math"
+)
+
+// line is n.p = d with unit normal n
+type line struct {
+	n geometry.Point
+	d float64
+}
+
+func lineThrough(a, b geometry.Point) line {
+	dir := b.Sub(a)
+	n := geometry.Point{X: -dir.Y, Y: dir.X}.Scale(1 / dir.Norm())
+	return line{n: n, d: n.Dot(a)}
+}
+
+func (l line) intersect(m line) (geometry.Point, bool) {
+	det := l.n.X*m.n.Y - l.n.Y*m.n.X
+	if math.Abs(det) < 1e-9 {
+		return geometry.Point{}, false
+	}
+	return geometry.Point{
+		X: (l.d*m.n.Y - m.d*l.n.Y) / det,
+		Y: (l.n.X*m.d - m.n.X*l.d) / det,
+	}, true
+}
+
+// fitSide refits the side a-b to the pixels within band of it whose sobel gradient is strong and
+// roughly along the side normal, weighting each pixel by its gradient magnitude. The ends of the
+// side are skipped since they run into the neighbouring sides.
+func fitSide(gray *imaging.Float, a, b geometry.Point, band float64) line {
+	guess := lineThrough(a, b)
+	dir := b.Sub(a)
+	length := dir.Norm()
+	dir = dir.Scale(1 / length)
+
+	minX := int(math.Max(1, math.Floor(math.Min(a.X, b.X)-band)))
+	maxX := int(math.Min(float64(gray.W-2), math.Ceil(math.Max(a.X, b.X)+band)))
+	minY := int(math.Max(1, math.Floor(math.Min(a.Y, b.Y)-band)))
+	maxY := int(math.Min(float64(gray.H-2), math.Ceil(math.Max(a.Y, b.Y)+band)))
+
+	type sample struct {
+		p geometry.Point
+		w float64
+	}
+	samples := make([]sample, 0)
+	maxMag := 0.0
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			p := geometry.Point{X: float64(x), Y: float64(y)}
+			t := p.Sub(a).Dot(dir)
+			if t < 0.1*length || t > 0.9*length || math.Abs(guess.n.Dot(p)-guess.d) > band {
+				continue
+			}
+			gx := gray.At(x+1, y-1) + 2*gray.At(x+1, y) + gray.At(x+1, y+1) - gray.At(x-1, y-1) - 2*gray.At(x-1, y) - gray.At(x-1, y+1)
+			gy := gray.At(x-1, y+1) + 2*gray.At(x, y+1) + gray.At(x+1, y+1) - gray.At(x-1, y-1) - 2*gray.At(x, y-1) - gray.At(x+1, y-1)
+			mag := math.Hypot(gx, gy)
+			if mag == 0 || math.Abs(gx*guess.n.X+gy*guess.n.Y)/mag < math.Cos(math.Pi/8) {
+				continue
+			}
+			samples = append(samples, sample{p, mag})
+			maxMag = math.Max(maxMag, mag)
+		}
+	}
+
+	var mean geometry.Point
+	total := 0.0
+	for _, s := range samples {
+		if s.w >= maxMag/3 {
+			mean = mean.Add(s.p.Scale(s.w))
+			total += s.w
+		}
+	}
+	if total == 0 {
+		return guess
+	}
+	mean = mean.Scale(1 / total)
+	var sxx, syy, sxy float64
+	for _, s := range samples {
+		if s.w >= maxMag/3 {
+			d := s.p.Sub(mean)
+			sxx += s.w * d.X * d.X
+			syy += s.w * d.Y * d.Y
+			sxy += s.w * d.X * d.Y
+		}
+	}
+	// the normal is the eigenvector of the scatter matrix with the smallest eigenvalue
+	angle := 0.5*math.Atan2(2*sxy, sxx-syy) + math.Pi/2
+	n := geometry.Point{X: math.Cos(angle), Y: math.Sin(angle)}
+	if n.Dot(guess.n) < 0 {
+		n = n.Scale(-1)
+	}
+	return line{n: n, d: n.Dot(mean)}
+}
@@ -3,26 +3,16 @@
 package fast
 
 import (
-	"fmt"
+	"Backend/src/corner"
+	"Backend/src/imaging"
 	"image"
 	"image/color"
-	"image/draw"
 	"image/jpeg"
 	"math"
 	"os"
 	"sort"
 )
 
-func getJPGImageFromFilePath(filePath string) (image.Image, error) {
-    f, err := os.Open(filePath)
-    if err != nil {
-        return nil, err
-    }
-    defer f.Close()
-    image, err := jpeg.Decode(f)
-    return image, err
-}
-
 func RgbToGray(img image.Image) *image.Gray {
 	bounds := img.Bounds()
 	gray := image.NewGray(bounds)
@@ -31,7 +21,7 @@ func RgbToGray(img image.Image) *image.Gray {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 			r, g, b, _ := img.At(x, y).RGBA()
 			// Weighted average for luminance perception to convert rgb to gray
-			grayVal := uint8(0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b) / 256) 
+			grayVal := uint8(0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)/256)
 			gray.Set(x, y, color.Gray{grayVal})
 		}
 	}
@@ -58,31 +48,31 @@ func SaveGrayImage(img *image.Gray, filePath string) error {
 }
 
 func Circle(row, col int) [8][2]int {
-    point1 := [2]int{row + 3, col}
-    point3 := [2]int{row + 3, col - 1}
-    point5 := [2]int{row + 1, col + 3}
-    point7 := [2]int{row - 1, col + 3}
-    point9 := [2]int{row - 3, col}
-    point11 := [2]int{row - 3, col - 1}
-    point13 := [2]int{row + 1, col - 3}
-    point15 := [2]int{row - 1, col - 3}
-
-    return [8][2]int{point1, point3, point5, point7, point9, point11, point13, point15}
+	point1 := [2]int{row + 3, col}
+	point3 := [2]int{row + 3, col - 1}
+	point5 := [2]int{row + 1, col + 3}
+	point7 := [2]int{row - 1, col + 3}
+	point9 := [2]int{row - 3, col}
+	point11 := [2]int{row - 3, col - 1}
+	point13 := [2]int{row + 1, col - 3}
+	point15 := [2]int{row - 1, col - 3}
+
+	return [8][2]int{point1, point3, point5, point7, point9, point11, point13, point15}
 }
 
-func AdjacencyCheck(p1 [2]int, p2[2]int) bool {
+func AdjacencyCheck(p1 [2]int, p2 [2]int) bool {
 	return (p1[0]-p2[0])*(p1[0]-p2[0])+(p1[1]-p2[1])*(p1[1]-p2[1]) < 9
 }
 
 func IsCorner(img *image.Gray, row, col int, ROI [8][2]int, threshold int) bool {
 	// Central pixel intensity
-	I := img.GrayAt(row, col).Y
+	I := int(img.GrayAt(row, col).Y)
 
 	// Count pixels that meet the threshold condition
 	count := 0
 	for _, point := range ROI {
 		neighborRow, neighborCol := point[0], point[1]
-		if math.Abs(float64(img.GrayAt(neighborRow, neighborCol).Y-I)) > float64(threshold) {
+		if math.Abs(float64(int(img.GrayAt(neighborRow, neighborCol).Y)-I)) > float64(threshold) {
 			count++
 			if count >= 3 { // Early exit if corner condition is met
 				return true
@@ -99,7 +89,7 @@ func ScoreCheck(image *image.Gray, corner [2]int) int {
 	ROI := Circle(corner[0], corner[1])
 
 	// Center pixel intensity
-	centerIntensity := int(image.GrayAt(corner[1], corner[0]).Y)
+	centerIntensity := int(image.GrayAt(corner[0], corner[1]).Y)
 
 	// Calculate the score
 	score := 0
@@ -115,35 +105,37 @@ func ScoreCheck(image *image.Gray, corner [2]int) int {
 	return score
 }
 
-
 func Remove(slice [][2]int, s int) [][2]int {
-    return append(slice[:s], slice[s+1:]...)
+	return append(slice[:s], slice[s+1:]...)
 }
 
-func Fast(inputPath, outputPath string) {
-    img, err := getJPGImageFromFilePath(inputPath)
-	if err != nil {
-		fmt.Println("Error: ", err)
-		return
-	}
-	// Convert image to grayscale, taking dimensions and fixing region in which corner detection will be performed
-	gray := RgbToGray(img)
-	bounds := gray.Bounds()
-	minX := (bounds.Min.X)/4
-	maxX := 3 * (bounds.Max.X)/4
-	minY := (bounds.Min.Y)/4
-	maxY := 3 * (bounds.Max.Y)/4
+type Options struct {
+	Threshold int // intensity difference a circle pixel needs to count towards a corner
 
-	// fmt.Println(gray)
+	Maps *imaging.Maps // when set, collects the score of every pixel in the search region
+}
 
-	// apply median filter for salt and pepper noise
+func DefaultOptions() Options {
+	return Options{Threshold: 125}
+}
+
+// MedianFilter applies the 3x3 median filter for salt and pepper noise in place to the region where corners
+// are searched. The preprocess package offers the same filter, and others, on a copy.
+func MedianFilter(gray *image.Gray) {
+	bounds := gray.Bounds()
+	// read from a snapshot so filtered pixels do not feed into their neighbours' medians
+	src := &image.Gray{Pix: append([]uint8(nil), gray.Pix...), Stride: gray.Stride, Rect: gray.Rect}
+	minX := (bounds.Min.X) / 4
+	maxX := 3 * (bounds.Max.X) / 4
+	minY := (bounds.Min.Y) / 4
+	maxY := 3 * (bounds.Max.Y) / 4
 
-	for y:= minY; y < maxY; y++ {
-		for x:= minX; x < maxX; x++ {
+	for y := minY; y < maxY; y++ {
+		for x := minX; x < maxX; x++ {
 			temp := make([]int, 0, 9)
 			for i := -1; i <= 1; i++ {
 				for j := -1; j <= 1; j++ {
-					px := gray.GrayAt(x+i, y+j).Y
+					px := src.GrayAt(x+i, y+j).Y
 					temp = append(temp, int(px))
 				}
 			}
@@ -151,8 +143,28 @@ func Fast(inputPath, outputPath string) {
 			gray.SetGray(x, y, color.Gray{uint8(medianValue)})
 		}
 	}
+}
+
+// Detect runs the FAST test on the search region of a grayscale image, scoring every corner by the
+// summed intensity difference to its circle
+func Detect(gray *image.Gray, opts Options) []corner.Corner {
+	return DetectFloat(imaging.ToFloat(gray), opts)
+}
+
+// DetectFloat is Detect on a float image on the 0..255 scale, so 16 bit and float inputs keep their
+// precision while Threshold still means the same fraction of full intensity
+func DetectFloat(gray *imaging.Float, opts Options) []corner.Corner {
+	minX, maxX := 0, 3*gray.W/4
+	minY, maxY := 0, 3*gray.H/4
+	// pixels outside the image read as black, like GrayAt does
+	at := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= gray.W || y >= gray.H {
+			return 0
+		}
+		return gray.At(x, y)
+	}
+	threshold := float64(opts.Threshold)
 
-	
 	// Iterate through the image and apply the FAST corner detection algorithm
 	Corners := make([][2]int, 0)
 
@@ -161,69 +173,51 @@ func Fast(inputPath, outputPath string) {
 			// Check if the pixel is a corner
 			// draw a circle around it
 			// check that it has a certain number of pixels with good intensity difference
-
-
-			ROI := Circle(x, y) // why we are putting ulta here check
-			if IsCorner(gray, x, y, ROI, 125) {
+			I := at(x, y)
+			count := 0
+			for _, point := range Circle(x, y) {
+				if math.Abs(at(point[0], point[1])-I) > threshold {
+					count++
+				}
+			}
+			if count >= 3 {
 				Corners = append(Corners, [2]int{x, y})
 			}
 		}
 	}
 
-	// reducedCorners := make([][2]int, 0)
-	// Non-maximum suppression
-	for i := 1; i < len(Corners)-1; i++{
-		if AdjacencyCheck(Corners[i], Corners[i+1]) {
-			score1 := ScoreCheck(gray, Corners[i-1])
-			score2 := ScoreCheck(gray, Corners[i])
-
-			if score1 < score2 {
-				Remove(Corners, i-1)
-			}else {
-				Remove(Corners, i)
+	score := func(x, y int) float64 {
+		sum := 0.0
+		for _, p := range Circle(x, y) {
+			// circle pixels outside the image do not count towards the score
+			if p[0] >= 0 && p[1] >= 0 && p[0] < gray.W && p[1] < gray.H {
+				sum += math.Abs(at(x, y) - at(p[0], p[1]))
 			}
-			
-		}else{ 
-			i += 1;
-			continue
 		}
+		return sum
 	}
-
-	err = SaveGrayImage(gray, "processed_corner3.jpeg")
-	if err != nil {
-		fmt.Println("Error saving image: ", err)
-	}
-
-	fmt.Println(Corners)
-
-
-	// Make a new image and draw all the points on it
-	bounds1 := img.Bounds()
-	rgba := image.NewRGBA(bounds)
-	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
-
-	pointColor := color.RGBA{255, 0, 0, 255} // Red color
-	for _, point := range Corners {
-		x, y := point[0], point[1]
-		if x >= bounds1.Min.X && x < bounds1.Max.X && y >= bounds1.Min.Y && y < bounds1.Max.Y {
-			rgba.Set(x, y, pointColor)
+	if opts.Maps.Enabled() {
+		scores := imaging.NewFloat(gray.W, gray.H)
+		for y := minY; y < maxY; y++ {
+			for x := minX; x < maxX; x++ {
+				scores.Set(x, y, score(x, y))
+			}
 		}
+		opts.Maps.Add("fast_score", scores)
 	}
 
-	// Save the new image
-	outFile, err := os.Create(outputPath)
-	if err != nil {
-		fmt.Println("Error creating output file:", err)
-		return
-	}
-	defer outFile.Close()
-
-	err = jpeg.Encode(outFile, rgba, nil)
-	if err != nil {
-		fmt.Println("Error encoding image:", err)
-		return
+	// Non-maximum suppression, of two adjacent corners found one after the other keep the higher score
+	kept := make([]corner.Corner, 0, len(Corners))
+	for i, point := range Corners {
+		score := score(point[0], point[1])
+		if i > 0 && AdjacencyCheck(Corners[i-1], point) && len(kept) > 0 {
+			last := &kept[len(kept)-1]
+			if score > last.Score {
+				*last = corner.Corner{X: float64(point[0]), Y: float64(point[1]), Score: score}
+			}
+			continue
+		}
+		kept = append(kept, corner.Corner{X: float64(point[0]), Y: float64(point[1]), Score: score})
 	}
-
-	fmt.Println("Modified image saved as:", outputPath)
-
+	return kept
 }
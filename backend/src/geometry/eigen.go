@@ -0,0 +1,103 @@
+// eigen decomposition of small symmetric matrices with the cyclic jacobi method
+
+package geometry
+
+import (
+	"math"
+	"sort"
+)
+
+// SymmetricEigen returns the eigenvalues of the symmetric matrix a in ascending order, with the
+// matching unit eigenvectors as the columns of vectors. a is not modified.
+func SymmetricEigen(a [][]float64) ([]float64, [][]float64) {
+	n := len(a)
+	m := make([][]float64, n)
+	v := make([][]float64, n)
+	for i := range m {
+		m[i] = append([]float64(nil), a[i]...)
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+
+	for sweep := 0; sweep < 100; sweep++ {
+		off := 0.0
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += m[i][j] * m[i][j]
+			}
+		}
+		if off < 1e-30 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if m[p][q] == 0 {
+					continue
+				}
+				theta := (m[q][q] - m[p][p]) / (2 * m[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					mkp, mkq := m[k][p], m[k][q]
+					m[k][p] = c*mkp - s*mkq
+					m[k][q] = s*mkp + c*mkq
+				}
+				for k := 0; k < n; k++ {
+					mpk, mqk := m[p][k], m[q][k]
+					m[p][k] = c*mpk - s*mqk
+					m[q][k] = s*mpk + c*mqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return m[order[i]][order[i]] < m[order[j]][order[j]] })
+	values := make([]float64, n)
+	vectors := make([][]float64, n)
+	for i := range vectors {
+		vectors[i] = make([]float64, n)
+	}
+	for col, idx := range order {
+		values[col] = m[idx][idx]
+		for row := 0; row < n; row++ {
+			vectors[row][col] = v[row][idx]
+		}
+	}
+	return values, vectors
+}
+
+// NullVector returns the unit vector x minimizing |a*x| for the rows of a, which is the eigenvector
+// of a^T*a with the smallest eigenvalue
+func NullVector(a [][]float64) []float64 {
+	n := len(a[0])
+	ata := make([][]float64, n)
+	for i := range ata {
+		ata[i] = make([]float64, n)
+	}
+	for _, row := range a {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				ata[i][j] += row[i] * row[j]
+			}
+		}
+	}
+	_, vectors := SymmetricEigen(ata)
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = vectors[i][0]
+	}
+	return x
+}
@@ -0,0 +1,100 @@
+// planar homographies stored row major, mapping (x, y, 1) to (x', y', w')
+
+package geometry
+
+import "math"
+
+type Homography [9]float64
+
+func Identity() Homography {
+	return Homography{1, 0, 0, 0, 1, 0, 0, 0, 1}
+}
+
+func (h Homography) Apply(p Point) Point {
+	w := h[6]*p.X + h[7]*p.Y + h[8]
+	return Point{
+		X: (h[0]*p.X + h[1]*p.Y + h[2]) / w,
+		Y: (h[3]*p.X + h[4]*p.Y + h[5]) / w,
+	}
+}
+
+// Mul returns the composition h*g, which applies g first
+func (h Homography) Mul(g Homography) Homography {
+	var out Homography
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			for k := 0; k < 3; k++ {
+				out[3*r+c] += h[3*r+k] * g[3*k+c]
+			}
+		}
+	}
+	return out
+}
+
+func (h Homography) Inverse() (Homography, error) {
+	det := h[0]*(h[4]*h[8]-h[5]*h[7]) - h[1]*(h[3]*h[8]-h[5]*h[6]) + h[2]*(h[3]*h[7]-h[4]*h[6])
+	if det == 0 {
+		return Homography{}, ErrSingular
+	}
+	inv := Homography{
+		h[4]*h[8] - h[5]*h[7], h[2]*h[7] - h[1]*h[8], h[1]*h[5] - h[2]*h[4],
+		h[5]*h[6] - h[3]*h[8], h[0]*h[8] - h[2]*h[6], h[2]*h[3] - h[0]*h[5],
+		h[3]*h[7] - h[4]*h[6], h[1]*h[6] - h[0]*h[7], h[0]*h[4] - h[1]*h[3],
+	}
+	for i := range inv {
+		inv[i] /= det
+	}
+	return inv, nil
+}
+
+// normalization returns the similarity moving points to zero mean and sqrt(2) mean distance,
+// which keeps the linear homography estimate well conditioned
+func normalization(points []Point) Homography {
+	var mean Point
+	for _, p := range points {
+		mean = mean.Add(p)
+	}
+	mean = mean.Scale(1 / float64(len(points)))
+	dist := 0.0
+	for _, p := range points {
+		dist += p.Dist(mean)
+	}
+	dist /= float64(len(points))
+	if dist == 0 {
+		dist = 1
+	}
+	s := math.Sqrt2 / dist
+	return Homography{s, 0, -s * mean.X, 0, s, -s * mean.Y, 0, 0, 1}
+}
+
+// EstimateHomography finds the homography mapping src onto dst in the least squares sense with the
+// normalized direct linear transform. At least four correspondences are needed.
+func EstimateHomography(src, dst []Point) (Homography, error) {
+	if len(src) != len(dst) || len(src) < 4 {
+		return Homography{}, ErrTooFewPoints
+	}
+	ns, nd := normalization(src), normalization(dst)
+	rows := make([][]float64, 0, 2*len(src))
+	for i := range src {
+		s, d := ns.Apply(src[i]), nd.Apply(dst[i])
+		rows = append(rows,
+			[]float64{-s.X, -s.Y, -1, 0, 0, 0, d.X * s.X, d.X * s.Y, d.X},
+			[]float64{0, 0, 0, -s.X, -s.Y, -1, d.Y * s.X, d.Y * s.Y, d.Y},
+		)
+	}
+	var h Homography
+	copy(h[:], NullVector(rows))
+
+	ndInv, err := nd.Inverse()
+	if err != nil {
+		return Homography{}, err
+	}
+	h = ndInv.Mul(h).Mul(ns)
+	if h[8] == 0 {
+		return Homography{}, ErrSingular
+	}
+	for i := range h {
+		h[i] /= h[8]
+	}
+	return h, nil
+}
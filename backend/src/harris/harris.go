@@ -3,52 +3,15 @@
 package harris
 
 import (
+	"Backend/src/corner"
+	"Backend/src/imaging"
 	"fmt"
 	"image"
 	"image/color"
-	"image/draw"
-	"image/jpeg"
-	"image/png"
 	"math"
-	"os"
 	"sort"
 )
 
-func getPNGImageFromFilePath(filePath string) (image.Image, error) {
-	f, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	image, err := png.Decode(f)
-	return image, err
-}
-
-func getJPGImageFromFilePath(filePath string) (image.Image, error) {
-    f, err := os.Open(filePath)
-    if err != nil {
-        return nil, err
-    }
-    defer f.Close()
-    image, err := jpeg.Decode(f)
-    return image, err
-}
-
-func rgbToGray(img image.Image) *image.Gray {
-	bounds := img.Bounds()
-	gray := image.NewGray(bounds)
-
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r, g, b, _ := img.At(x, y).RGBA()
-			// Weighted average for luminance perception to convert rgb to gray
-			grayVal := uint8(0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)/256)
-			gray.Set(x, y, color.Gray{grayVal})
-		}
-	}
-	return gray
-}
-
 func median(arr []int) int {
 	sort.Ints(arr)
 	mid := len(arr) / 2
@@ -67,89 +30,72 @@ func median(arr []int) int {
 // 	return png.Encode(f, img)
 // }
 
-func convolution(img image.Image, kernel [3][3]int, minX int, minY int, maxX int, maxY int) [][]int {
+// convolution applies a 3x3 kernel over the search region of a gray image, returning signed responses
+func convolution(gray *imaging.Float, kernel [3][3]int, minX int, minY int, maxX int, maxY int) [][]float64 {
 
-	newImg := make([][]int, maxY)
+	newImg := make([][]float64, maxY)
 	for i := range newImg {
-		newImg[i] = make([]int, maxX)
+		newImg[i] = make([]float64, maxX)
 	}
 
 	// Taking convolution at each point
 	for y := minY; y < maxY; y++ {
 		for x := minX; x < maxX; x++ {
 			// Convolution with  kernel
-			var sum int = 0
+			var sum float64 = 0
 			for i := -1; i < 2; i++ {
 				for j := -1; j < 2; j++ {
 					// Handle boundary conditions
 					imgX := x + i
 					imgY := y + j
-					
+
 					if imgX >= minX && imgX < maxX && imgY >= minY && imgY < maxY {
-						r, g, b, _ := img.At(imgX, imgY).RGBA()
-						grayVal := uint8(0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)/256)
-						sum += int(grayVal) * kernel[j+1][i+1]
-						// temp = append(temp, int(px))
+						sum += gray.At(imgX, imgY) * float64(kernel[j+1][i+1])
 					}
 				}
 			}
-
-			if sum < 0 {
-				sum = 0
-			} else if sum > 255 {
-				sum = 255
-			}
-			newImg[y][x] = sum 
+			newImg[y][x] = sum
 		}
 	}
 	return newImg
 }
 
-func Harris(inputPath, outputPath string) {
-    img, err := getJPGImageFromFilePath(inputPath)
-	if err != nil {
-		fmt.Println("Error: ", err)
-		return
-	}
-	// Convert image to grayscale, taking dimensions and fixing region in which corner detection will be performed
-	gray := rgbToGray(img)
-	bounds := gray.Bounds()
-	minX := (bounds.Min.X) / 4
-	maxX := 3 * (bounds.Max.X) / 4
-	minY := (bounds.Min.Y) / 4
-	maxY := 3 * (bounds.Max.Y) / 4
-	window := 3
-	threshold := 10 //threshold to pass for harris
-	minDist := 10   //the minimum distance between any 2 points
+type Options struct {
+	Window    int     // side of the window the squared gradients are summed over
+	K         float64 // harris sensitivity constant
+	Threshold float64 // minimum corner response
+	MinDist   float64 // the minimum distance between any 2 points
 
-	Corners := make([][3]float64, 0)
+	Maps *imaging.Maps // when set, collects the gradients, their products and the response map
+}
 
-	// apply median filter for salt and pepper noise
+func DefaultOptions() Options {
+	return Options{Window: 3, K: 0.04, Threshold: 10, MinDist: 10}
+}
 
-	for y := minY; y < maxY; y++ {
-		for x := minX; x < maxX; x++ {
-			temp := make([]int, 0, 9)
-			for i := -1; i <= 1; i++ {
-				for j := -1; j <= 1; j++ {
-					px := gray.GrayAt(x+i, y+j).Y
-					temp = append(temp, int(px))
-				}
-			}
-			medianValue := median(temp)
-			gray.SetGray(x, y, color.Gray{uint8(medianValue)})
-		}
-	}
+// Detect computes the harris response det(M) - k*trace(M)^2 over the search region of a grayscale
+// image and returns the strongest corners that are at least MinDist apart
+func Detect(gray *image.Gray, opts Options) []corner.Corner {
+	return DetectChannels([]*imaging.Float{imaging.ToFloat(gray)}, opts)
+}
+
+// DetectChannels is Detect for a multi channel image given as one float image per channel, each on
+// the 0..255 scale so 16 bit and float inputs keep their precision under the same thresholds. The
+// structure tensors of the channels are summed (Di Zenzo) so corners between colors of equal
+// luminance, which vanish in a gray conversion, still respond.
+func DetectChannels(channels []*imaging.Float, opts Options) []corner.Corner {
+	// taking dimensions and fixing region in which corner detection will be performed
+	minX := 0
+	maxX := 3 * channels[0].W / 4
+	minY := 0
+	maxY := 3 * channels[0].H / 4
+	window := opts.Window
+
+	Corners := make([]corner.Corner, 0)
 
 	// applying harris corner detection algorithm on each point in image
 	// finding derivatives of each point and making differential square matrix
 
-	// Laplace Kernel
-	// kernel := [3][3]int{
-	//     {1, 1, 1},
-	//     {1, -8, 1},
-	//     {1, 1, 1},
-	// }
-
 	// sobel matrices that are used to calculate derivative matrices using convolution
 	sobelX := [3][3]int{
 		{-1, 0, 1},
@@ -162,117 +108,136 @@ func Harris(inputPath, outputPath string) {
 		{0, 0, 0},
 		{1, 2, 1},
 	}
-	Ixx := make([][]int, maxY)
-	Iyy := make([][]int, maxY)
-	Ixy := make([][]int, maxY)
+	Ixx := make([][]float64, maxY)
+	Iyy := make([][]float64, maxY)
+	Ixy := make([][]float64, maxY)
 	for i := range Ixx {
-		Ixx[i] = make([]int, maxX)
-		Iyy[i] = make([]int, maxX)
-		Ixy[i] = make([]int, maxX)
-	}
-
-	// dx and dy using Sobel kernels
-	dx := convolution(gray, sobelX, minX, minY, maxX, maxY)
-	dy := convolution(gray, sobelY, minX, minY, maxX, maxY)
-
+		Ixx[i] = make([]float64, maxX)
+		Iyy[i] = make([]float64, maxX)
+		Ixy[i] = make([]float64, maxX)
+	}
+
+	for c, gray := range channels {
+		// dx and dy using Sobel kernels
+		dx := convolution(gray, sobelX, minX, minY, maxX, maxY)
+		dy := convolution(gray, sobelY, minX, minY, maxX, maxY)
+		if opts.Maps.Enabled() {
+			suffix := ""
+			if len(channels) > 1 {
+				suffix = fmt.Sprintf("_%d", c)
+			}
+			opts.Maps.AddRows("dx"+suffix, dx, channels[0].W, channels[0].H)
+			opts.Maps.AddRows("dy"+suffix, dy, channels[0].W, channels[0].H)
+		}
 
-	//  Ixx and Iyy (squared gradients)
-	for y := 0; y < maxY; y++ {
-		for x := 0; x < maxX; x++ {
-			Ixx[y][x] = dx[y][x] * dx[y][x]
-			Iyy[y][x] = dy[y][x] * dy[y][x]
-			Ixy[y][x] = dx[y][x] * dy[y][x]
+		//  Ixx and Iyy (squared gradients), summed over the channels
+		for y := 0; y < maxY; y++ {
+			for x := 0; x < maxX; x++ {
+				Ixx[y][x] += dx[y][x] * dx[y][x]
+				Iyy[y][x] += dy[y][x] * dy[y][x]
+				Ixy[y][x] += dx[y][x] * dy[y][x]
+			}
 		}
 	}
 
-	Sxx := make([][]int, maxY)
-	Syy := make([][]int, maxY)
-	Sxy := make([][]int, maxY)
-	for i := range Sxx {
-    	Sxx[i] = make([]int, maxX)
-    	Syy[i] = make([]int, maxX)
-    	Sxy[i] = make([]int, maxX)
-	}
-	det := make([][]float64, maxY)
-	trace := make([][]float64, maxY)
-	for i := range det {
-    	det[i] = make([]float64, maxX)
-    	trace[i] = make([]float64, maxX)
+	var responses [][]float64
+	if opts.Maps.Enabled() {
+		w, h := channels[0].W, channels[0].H
+		opts.Maps.AddRows("ixx", Ixx, w, h)
+		opts.Maps.AddRows("iyy", Iyy, w, h)
+		opts.Maps.AddRows("ixy", Ixy, w, h)
+		responses = make([][]float64, maxY)
+		for i := range responses {
+			responses[i] = make([]float64, maxX)
+		}
 	}
 
-
 	// Sum of square gradients in window and finding the corners
 	for y := window; y < maxY-window; y++ {
 		for x := window; x < maxX-window; x++ {
+			var Sxx, Syy, Sxy float64
 			for i := 0; i < window; i++ {
 				for j := 0; j < window; j++ {
-					if y+i < maxY && x+j < maxX {
-						Sxx[y][x] += Ixx[y+i][x+j]
-						Syy[y][x] += Iyy[y+i][x+j]
-						Sxy[y][x] += Ixy[y+i][x+j]
-					}
+					Sxx += Ixx[y+i][x+j]
+					Syy += Iyy[y+i][x+j]
+					Sxy += Ixy[y+i][x+j]
 				}
 			}
 			// Determinant and trace
-			det[y][x] = float64((Sxx[y][x] * Syy[y][x]) - (Sxy[y][x] * Sxy[y][x]))
-			trace[y][x] = float64(Sxx[y][x] + Syy[y][x])
-	
-			k := 0.04 // suitable constant
-			r := det[y][x] - k*(trace[y][x])
-	
-			if r < float64(threshold) {
-				Corners = append(Corners, [3]float64{float64(x), float64(y), r})
+			det := Sxx*Syy - Sxy*Sxy
+			trace := Sxx + Syy
+			r := det - opts.K*trace*trace
+
+			if responses != nil {
+				responses[y][x] = r
+			}
+			if r > opts.Threshold {
+				Corners = append(Corners, corner.Corner{X: float64(x), Y: float64(y), Score: r})
 			}
 		}
 	}
-	
+
+	if responses != nil {
+		opts.Maps.AddRows("harris_r", responses, channels[0].W, channels[0].H)
+	}
 
 	sort.Slice(Corners, func(i, j int) bool {
-		return Corners[i][2] > Corners[j][2]
+		return Corners[i].Score > Corners[j].Score
 	})
 
-	// Filter corners by distance
-	eFiltered := [][3]float64{Corners[0]} //the final filtered set of corners
-	for _, corner := range Corners {
+	return filterByDistance(Corners, opts.MinDist)
+}
+
+// filterByDistance keeps corners, strongest first, that are farther than minDist from every corner
+// kept before. Kept corners are bucketed in a grid of minDist cells so only nearby ones are compared.
+func filterByDistance(Corners []corner.Corner, minDist float64) []corner.Corner {
+	eFiltered := make([]corner.Corner, 0) //the final filtered set of corners
+	cellSize := math.Max(minDist, 1)
+	grid := make(map[[2]int][]int)
+	for _, c := range Corners {
+		cx, cy := int(c.X/cellSize), int(c.Y/cellSize)
 		bigger := true
-		for _, filteredCorner := range eFiltered {
-			// Euclidean distance comparison
-			distance := math.Sqrt(math.Pow(corner[0]-filteredCorner[0], 2) + math.Pow(corner[1]-filteredCorner[1], 2))
-			if distance <= float64(minDist) {
-				bigger = false
-				break
+		for gy := cy - 1; gy <= cy+1 && bigger; gy++ {
+			for gx := cx - 1; gx <= cx+1; gx++ {
+				for _, k := range grid[[2]int{gx, gy}] {
+					// Euclidean distance comparison
+					if math.Hypot(c.X-eFiltered[k].X, c.Y-eFiltered[k].Y) <= minDist {
+						bigger = false
+						break
+					}
+				}
 			}
 		}
 		if bigger {
-			eFiltered = append(eFiltered, corner)
+			grid[[2]int{cx, cy}] = append(grid[[2]int{cx, cy}], len(eFiltered))
+			eFiltered = append(eFiltered, c)
 		}
 	}
+	return eFiltered
+}
 
-	// Make a new image and draw all the points on it
-	rgba := image.NewRGBA(bounds)
-	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+// MedianFilter applies the 3x3 median filter for salt and pepper noise in place to the region where corners
+// are searched. The preprocess package offers the same filter, and others, on a copy.
+func MedianFilter(gray *image.Gray) {
+	bounds := gray.Bounds()
+	// read from a snapshot so filtered pixels do not feed into their neighbours' medians
+	src := &image.Gray{Pix: append([]uint8(nil), gray.Pix...), Stride: gray.Stride, Rect: gray.Rect}
+	minX := (bounds.Min.X) / 4
+	maxX := 3 * (bounds.Max.X) / 4
+	minY := (bounds.Min.Y) / 4
+	maxY := 3 * (bounds.Max.Y) / 4
 
-	pointColor := color.RGBA{255, 0, 0, 255} // Red color
-	for _, point := range Corners {
-		x, y := point[0], point[1]
-		if x >= float64(minX) && x < float64(maxX) && y >= float64(minY) && y < float64(maxY) {
-			rgba.Set(int(x), int(y), pointColor)
+	for y := minY; y < maxY; y++ {
+		for x := minX; x < maxX; x++ {
+			temp := make([]int, 0, 9)
+			for i := -1; i <= 1; i++ {
+				for j := -1; j <= 1; j++ {
+					px := src.GrayAt(x+i, y+j).Y
+					temp = append(temp, int(px))
+				}
+			}
+			medianValue := median(temp)
+			gray.SetGray(x, y, color.Gray{uint8(medianValue)})
 		}
 	}
-
-	// Save the new image
-	outFile, err := os.Create(outputPath)
-	if err != nil {
-		fmt.Println("Error creating output file:", err)
-		return
-	}
-	defer outFile.Close()
-
-	err = png.Encode(outFile, rgba)
-	if err != nil {
-		fmt.Println("Error encoding image:", err)
-		return
-	}
-
-	fmt.Println("Modified image saved as:", outputPath)
 }
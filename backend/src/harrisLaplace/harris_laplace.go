@@ -0,0 +1,152 @@
+// implementation of scale adapted harris-laplace (and shi-tomasi-laplace) corner detection in go
+//
+// The cornerness measure is computed at a set of integration scales sigmaI = Sigma0*Step^n with a
+// differentiation scale sigmaD = DiffRatio*sigmaI. A spatial maximum of the measure is kept only at
+// the scales where the scale normalized laplacian sigma^2 |Lxx + Lyy| peaks, which gives every corner
+// a characteristic scale that descriptors can use to size their support region.
+
+package harrisLaplace
+
+import (
+	"Backend/src/corner"
+	"Backend/src/imaging"
+	"fmt"
+	"math"
+	"sort"
+)
+
+type Measure int
+
+const (
+	Harris    Measure = iota // det(M) - k*trace(M)^2
+	ShiTomasi                // smallest eigenvalue of M
+)
+
+type Options struct {
+	Measure    Measure
+	Sigma0     float64 // first integration scale
+	Step       float64 // ratio between consecutive scales
+	Scales     int     // number of scales
+	DiffRatio  float64 // differentiation scale as a fraction of the integration scale
+	K          float64 // harris constant
+	Threshold  float64 // fraction of the strongest response a corner must reach at its scale
+	MaxCorners int     // keep only the strongest corners, 0 keeps all
+
+	Maps *imaging.Maps // when set, collects the response and laplacian of every scale
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Measure:   Harris,
+		Sigma0:    1.5,
+		Step:      1.4,
+		Scales:    8,
+		DiffRatio: 0.7,
+		K:         0.04,
+		Threshold: 0.01,
+	}
+}
+
+// scaleLevel holds the cornerness and normalized laplacian at one integration scale
+type scaleLevel struct {
+	sigma     float64
+	response  *imaging.Float
+	laplacian *imaging.Float
+}
+
+func buildLevel(gray *imaging.Float, sigmaI float64, opts Options) scaleLevel {
+	sigmaD := opts.DiffRatio * sigmaI
+	smooth := imaging.Gaussian(gray, sigmaD)
+	dx, dy := imaging.Derivatives(smooth)
+	sxx, syy, sxy := imaging.StructureTensor(dx, dy, sigmaI)
+
+	// sigmaD^2 normalizes the gradient products so responses are comparable across scales
+	norm := sigmaD * sigmaD
+	response := imaging.NewFloat(gray.W, gray.H)
+	for i := range response.Pix {
+		a, b, c := norm*sxx.Pix[i], norm*syy.Pix[i], norm*sxy.Pix[i]
+		det := a*b - c*c
+		trace := a + b
+		if opts.Measure == ShiTomasi {
+			response.Pix[i] = trace/2 - math.Sqrt(math.Max(trace*trace/4-det, 0))
+		} else {
+			response.Pix[i] = det - opts.K*trace*trace
+		}
+	}
+
+	smoothI := imaging.Gaussian(gray, sigmaI)
+	lxx, lyy, _ := imaging.SecondDerivatives(smoothI)
+	laplacian := imaging.NewFloat(gray.W, gray.H)
+	for i := range laplacian.Pix {
+		laplacian.Pix[i] = sigmaI * sigmaI * math.Abs(lxx.Pix[i]+lyy.Pix[i])
+	}
+	return scaleLevel{sigma: sigmaI, response: response, laplacian: laplacian}
+}
+
+// Detect finds scale invariant corners in a grayscale image
+func Detect(gray *imaging.Float, opts Options) []corner.Corner {
+	if opts.Scales < 1 {
+		opts.Scales = 1
+	}
+	levels := make([]scaleLevel, opts.Scales)
+	for n := range levels {
+		levels[n] = buildLevel(gray, opts.Sigma0*math.Pow(opts.Step, float64(n)), opts)
+		opts.Maps.Add(fmt.Sprintf("response_s%d", n), levels[n].response)
+		opts.Maps.Add(fmt.Sprintf("laplacian_s%d", n), levels[n].laplacian)
+	}
+
+	corners := make([]corner.Corner, 0)
+	for n, level := range levels {
+		_, max := level.response.MinMax()
+		if max <= 0 {
+			continue
+		}
+		border := int(math.Ceil(level.sigma))
+		for _, p := range imaging.LocalMaxima(level.response, opts.Threshold*max, border) {
+			x, y := p[0], p[1]
+			lap := level.laplacian.At(x, y)
+			// the laplacian has to be an extremum over the neighbouring scales
+			if n > 0 && levels[n-1].laplacian.At(x, y) >= lap {
+				continue
+			}
+			if n < len(levels)-1 && levels[n+1].laplacian.At(x, y) >= lap {
+				continue
+			}
+			corners = append(corners, corner.Corner{
+				X:     float64(x),
+				Y:     float64(y),
+				Score: level.response.At(x, y),
+				Scale: level.sigma,
+			})
+		}
+	}
+
+	sort.Slice(corners, func(i, j int) bool {
+		return corners[i].Score > corners[j].Score
+	})
+	if opts.MaxCorners > 0 && len(corners) > opts.MaxCorners {
+		corners = corners[:opts.MaxCorners]
+	}
+	return corners
+}
+
+func run(inputPath, outputPath string, opts Options) error {
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		return err
+	}
+	corners := Detect(imaging.ToFloat(img), opts)
+	return imaging.Save(corner.Draw(img, corners), outputPath)
+}
+
+// HarrisLaplace marks the harris-laplace corners of the image at inputPath and saves it to outputPath
+func HarrisLaplace(inputPath, outputPath string) error {
+	return run(inputPath, outputPath, DefaultOptions())
+}
+
+// ShiTomashiLaplace is HarrisLaplace with the smaller eigenvalue as the corner measure
+func ShiTomashiLaplace(inputPath, outputPath string) error {
+	opts := DefaultOptions()
+	opts.Measure = ShiTomasi
+	return run(inputPath, outputPath, opts)
+}
@@ -0,0 +1,77 @@
+// canny edge detection
+
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// Canny returns a binary edge map (255 on edges) of f. The image is smoothed with a gaussian of the
+// given sigma, sobel gradients are thinned by non-maximum suppression along the gradient direction,
+// and hysteresis keeps pixels above high plus pixels above low connected to them. Thresholds apply to
+// the sobel gradient magnitude, which reaches about 1000 on a black to white step.
+func Canny(f *Float, sigma, low, high float64) *image.Gray {
+	dx, dy := Sobel(Gaussian(f, sigma))
+	mag := NewFloat(f.W, f.H)
+	for i := range mag.Pix {
+		mag.Pix[i] = math.Hypot(dx.Pix[i], dy.Pix[i])
+	}
+
+	// non-maximum suppression with the direction quantized to 0, 45, 90 and 135 degrees
+	thin := NewFloat(f.W, f.H)
+	for y := 1; y < f.H-1; y++ {
+		for x := 1; x < f.W-1; x++ {
+			i := y*f.W + x
+			m := mag.Pix[i]
+			if m < low {
+				continue
+			}
+			angle := math.Atan2(dy.Pix[i], dx.Pix[i]) * 180 / math.Pi
+			if angle < 0 {
+				angle += 180
+			}
+			var ox, oy int
+			switch {
+			case angle < 22.5 || angle >= 157.5:
+				ox, oy = 1, 0
+			case angle < 67.5:
+				ox, oy = 1, 1
+			case angle < 112.5:
+				ox, oy = 0, 1
+			default:
+				ox, oy = -1, 1
+			}
+			if m >= mag.At(x+ox, y+oy) && m > mag.At(x-ox, y-oy) {
+				thin.Pix[i] = m
+			}
+		}
+	}
+
+	edges := image.NewGray(image.Rect(0, 0, f.W, f.H))
+	stack := make([]int, 0)
+	for i, m := range thin.Pix {
+		if m >= high && edges.Pix[i] == 0 {
+			edges.Pix[i] = 255
+			stack = append(stack, i)
+		}
+		for len(stack) > 0 {
+			j := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := j%f.W, j/f.W
+			for ny := y - 1; ny <= y+1; ny++ {
+				for nx := x - 1; nx <= x+1; nx++ {
+					if nx < 0 || ny < 0 || nx >= f.W || ny >= f.H {
+						continue
+					}
+					k := ny*f.W + nx
+					if edges.Pix[k] == 0 && thin.Pix[k] >= low {
+						edges.Pix[k] = 255
+						stack = append(stack, k)
+					}
+				}
+			}
+		}
+	}
+	return edges
+}
@@ -0,0 +1,81 @@
+// splitting color images into channels for color aware gradients
+
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+// ColorSpace selects the channels gradients are computed on
+type ColorSpace string
+
+const (
+	Gray ColorSpace = "gray" // a single luminance channel
+	RGB  ColorSpace = "rgb"  // red, green and blue
+	Lab  ColorSpace = "lab"  // CIE L*a*b*, roughly perceptually uniform
+)
+
+var ErrColorSpace = errors.New("unknown color space")
+
+// ParseColorSpace reads a color space name, treating the empty string as gray
+func ParseColorSpace(value string) (ColorSpace, error) {
+	switch ColorSpace(value) {
+	case "", Gray:
+		return Gray, nil
+	case RGB, Lab:
+		return ColorSpace(value), nil
+	}
+	return "", fmt.Errorf("%w %q", ErrColorSpace, value)
+}
+
+// Channels splits img into the channels of the given color space, each scaled to 0..255. For lab
+// L* is stretched from 0..100 and a*, b* are offset by 128, so a unit of every channel is comparable
+// to one gray level.
+func Channels(img image.Image, space ColorSpace) []*Float {
+	if space == "" || space == Gray {
+		return []*Float{ToFloat(img)}
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	channels := []*Float{NewFloat(w, h), NewFloat(w, h), NewFloat(w, h)}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			c := [3]float64{float64(r) / 257, float64(g) / 257, float64(bl) / 257}
+			if space == Lab {
+				l, a, bb := toLab(c[0], c[1], c[2])
+				c = [3]float64{l * 2.55, a + 128, bb + 128}
+			}
+			for i, ch := range channels {
+				ch.Pix[y*w+x] = c[i]
+			}
+		}
+	}
+	return channels
+}
+
+// toLab converts 0..255 sRGB to CIE L*a*b* under the D65 white point
+func toLab(r, g, b float64) (float64, float64, float64) {
+	linear := func(v float64) float64 {
+		v /= 255
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	lr, lg, lb := linear(r), linear(g), linear(b)
+	x := (0.4124*lr + 0.3576*lg + 0.1805*lb) / 0.95047
+	y := 0.2126*lr + 0.7152*lg + 0.0722*lb
+	z := (0.0193*lr + 0.1192*lg + 0.9505*lb) / 1.08883
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
@@ -0,0 +1,73 @@
+// colormaps for rendering float maps as heatmaps
+
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// Colormap maps values in [0, 1] to colors
+type Colormap string
+
+const (
+	Viridis Colormap = "viridis" // perceptually uniform, dark blue to yellow
+	Jet     Colormap = "jet"     // the classic rainbow, blue through green to red
+	Magma   Colormap = "magma"   // perceptually uniform, black through purple to pale yellow
+)
+
+var ErrColormap = errors.New("unknown colormap")
+
+// Colormaps lists the colormaps ParseColormap accepts
+var Colormaps = []Colormap{Viridis, Jet, Magma}
+
+// stops sample the perceptual colormaps at eleven evenly spaced points, as published with matplotlib
+var stops = map[Colormap][11][3]uint8{
+	Viridis: {
+		{0x44, 0x01, 0x54}, {0x48, 0x24, 0x75}, {0x41, 0x44, 0x87}, {0x35, 0x5f, 0x8d}, {0x2a, 0x78, 0x8e}, {0x21, 0x91, 0x8c},
+		{0x22, 0xa8, 0x84}, {0x44, 0xbf, 0x70}, {0x7a, 0xd1, 0x51}, {0xbd, 0xdf, 0x26}, {0xfd, 0xe7, 0x25},
+	},
+	Magma: {
+		{0x00, 0x00, 0x04}, {0x14, 0x0e, 0x36}, {0x3b, 0x0f, 0x70}, {0x64, 0x1a, 0x80}, {0x8c, 0x29, 0x81}, {0xb7, 0x37, 0x79},
+		{0xde, 0x49, 0x68}, {0xf7, 0x70, 0x5c}, {0xfe, 0x9f, 0x6d}, {0xfe, 0xcf, 0x92}, {0xfc, 0xfd, 0xbf},
+	},
+}
+
+// ParseColormap reads a colormap name, defaulting to viridis for the empty string
+func ParseColormap(value string) (Colormap, error) {
+	switch Colormap(value) {
+	case "":
+		return Viridis, nil
+	case Viridis, Jet, Magma:
+		return Colormap(value), nil
+	}
+	return "", fmt.Errorf("%w %q", ErrColormap, value)
+}
+
+// At returns the color for t, clamped to [0, 1]
+func (c Colormap) At(t float64) color.RGBA {
+	t = math.Max(0, math.Min(1, t))
+	if math.IsNaN(t) {
+		t = 0
+	}
+	if c == Jet {
+		channel := func(center float64) uint8 {
+			return clampUint8(255 * math.Max(0, math.Min(1, 1.5-math.Abs(4*t-center))))
+		}
+		return color.RGBA{channel(3), channel(2), channel(1), 255}
+	}
+	table, ok := stops[c]
+	if !ok {
+		table = stops[Viridis]
+	}
+	pos := t * float64(len(table)-1)
+	i := min(int(pos), len(table)-2)
+	a := pos - float64(i)
+	var out [3]uint8
+	for k := range out {
+		out[k] = clampUint8(float64(table[i][k])*(1-a) + float64(table[i+1][k])*a)
+	}
+	return color.RGBA{out[0], out[1], out[2], 255}
+}
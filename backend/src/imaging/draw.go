@@ -0,0 +1,33 @@
+// simple drawing on rgba images
+
+package imaging
+
+import (
+	"Backend/src/geometry"
+	"image"
+	"image/color"
+	"math"
+)
+
+// DrawLine draws a one pixel wide line from a to b, clipped to the image
+func DrawLine(img *image.RGBA, a, b geometry.Point, c color.Color) {
+	steps := int(math.Ceil(math.Max(math.Abs(b.X-a.X), math.Abs(b.Y-a.Y))))
+	for k := 0; k <= steps; k++ {
+		f := 0.0
+		if steps > 0 {
+			f = float64(k) / float64(steps)
+		}
+		p := image.Pt(int(math.Round(a.X+f*(b.X-a.X))), int(math.Round(a.Y+f*(b.Y-a.Y))))
+		if p.In(img.Rect) {
+			img.Set(p.X, p.Y, c)
+		}
+	}
+}
+
+// DrawInfiniteLine draws the part of a hough line that crosses the image
+func DrawInfiniteLine(img *image.RGBA, l Line, c color.Color) {
+	a, b := l.points()
+	d := b.Sub(a)
+	extent := float64(img.Rect.Dx() + img.Rect.Dy())
+	DrawLine(img, a.Sub(d.Scale(extent)), a.Add(d.Scale(extent)), c)
+}
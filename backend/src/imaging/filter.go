@@ -0,0 +1,159 @@
+// convolution, gaussian smoothing and derivative filters on float images
+
+package imaging
+
+import "math"
+
+// GaussianKernel returns a normalized 1D gaussian kernel covering 3 sigma on each side
+func GaussianKernel(sigma float64) []float64 {
+	radius := int(math.Ceil(3 * sigma))
+	if radius < 1 {
+		radius = 1
+	}
+	kernel := make([]float64, 2*radius+1)
+	sum := 0.0
+	for i := -radius; i <= radius; i++ {
+		v := math.Exp(-float64(i*i) / (2 * sigma * sigma))
+		kernel[i+radius] = v
+		sum += v
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	return kernel
+}
+
+// Separable convolves f with kx along rows and ky along columns, replicating the border
+func Separable(f *Float, kx, ky []float64) *Float {
+	tmp := NewFloat(f.W, f.H)
+	row := make([]float64, 0)
+	for y := 0; y < f.H; y++ {
+		row = convolveLine(f.Pix[y*f.W:(y+1)*f.W], kx, row)
+		copy(tmp.Pix[y*f.W:], row)
+	}
+	out := NewFloat(f.W, f.H)
+	col := make([]float64, f.H)
+	for x := 0; x < f.W; x++ {
+		for y := 0; y < f.H; y++ {
+			col[y] = tmp.Pix[y*f.W+x]
+		}
+		row = convolveLine(col, ky, row)
+		for y := 0; y < f.H; y++ {
+			out.Pix[y*f.W+x] = row[y]
+		}
+	}
+	return out
+}
+
+// convolveLine convolves a single row or column, padding both ends with the edge value
+func convolveLine(line, kernel, out []float64) []float64 {
+	r := len(kernel) / 2
+	n := len(line)
+	padded := make([]float64, n+2*r)
+	for i := range padded {
+		j := i - r
+		if j < 0 {
+			j = 0
+		} else if j >= n {
+			j = n - 1
+		}
+		padded[i] = line[j]
+	}
+	out = out[:0]
+	for i := 0; i < n; i++ {
+		sum := 0.0
+		for k, w := range kernel {
+			sum += w * padded[i+k]
+		}
+		out = append(out, sum)
+	}
+	return out
+}
+
+// Gaussian smooths f with a gaussian of standard deviation sigma, returning f unchanged for sigma <= 0
+func Gaussian(f *Float, sigma float64) *Float {
+	if sigma <= 0 {
+		return f.Clone()
+	}
+	kernel := GaussianKernel(sigma)
+	return Separable(f, kernel, kernel)
+}
+
+// Sobel computes the horizontal and vertical derivatives of f with the 3x3 sobel kernels
+func Sobel(f *Float) (*Float, *Float) {
+	smooth := []float64{1, 2, 1}
+	diff := []float64{-1, 0, 1}
+	return Separable(f, diff, smooth), Separable(f, smooth, diff)
+}
+
+// Derivatives computes the central difference first derivatives of f
+func Derivatives(f *Float) (*Float, *Float) {
+	dx := NewFloat(f.W, f.H)
+	dy := NewFloat(f.W, f.H)
+	for y := 0; y < f.H; y++ {
+		for x := 0; x < f.W; x++ {
+			dx.Pix[y*f.W+x] = (f.Clamped(x+1, y) - f.Clamped(x-1, y)) / 2
+			dy.Pix[y*f.W+x] = (f.Clamped(x, y+1) - f.Clamped(x, y-1)) / 2
+		}
+	}
+	return dx, dy
+}
+
+// SecondDerivatives computes Ixx, Iyy and Ixy of f with central differences
+func SecondDerivatives(f *Float) (*Float, *Float, *Float) {
+	dxx := NewFloat(f.W, f.H)
+	dyy := NewFloat(f.W, f.H)
+	dxy := NewFloat(f.W, f.H)
+	for y := 0; y < f.H; y++ {
+		for x := 0; x < f.W; x++ {
+			c := f.Clamped(x, y)
+			i := y*f.W + x
+			dxx.Pix[i] = f.Clamped(x+1, y) - 2*c + f.Clamped(x-1, y)
+			dyy.Pix[i] = f.Clamped(x, y+1) - 2*c + f.Clamped(x, y-1)
+			dxy.Pix[i] = (f.Clamped(x+1, y+1) - f.Clamped(x-1, y+1) - f.Clamped(x+1, y-1) + f.Clamped(x-1, y-1)) / 4
+		}
+	}
+	return dxx, dyy, dxy
+}
+
+// StructureTensor returns the gaussian weighted products of the gradients, Sxx, Syy and Sxy
+func StructureTensor(dx, dy *Float, sigma float64) (*Float, *Float, *Float) {
+	ixx := NewFloat(dx.W, dx.H)
+	iyy := NewFloat(dx.W, dx.H)
+	ixy := NewFloat(dx.W, dx.H)
+	for i := range dx.Pix {
+		ixx.Pix[i] = dx.Pix[i] * dx.Pix[i]
+		iyy.Pix[i] = dy.Pix[i] * dy.Pix[i]
+		ixy.Pix[i] = dx.Pix[i] * dy.Pix[i]
+	}
+	return Gaussian(ixx, sigma), Gaussian(iyy, sigma), Gaussian(ixy, sigma)
+}
+
+// LocalMaxima returns the positions of pixels strictly greater than their 8 neighbours and above threshold
+func LocalMaxima(f *Float, threshold float64, border int) [][2]int {
+	if border < 1 {
+		border = 1
+	}
+	points := make([][2]int, 0)
+	for y := border; y < f.H-border; y++ {
+		for x := border; x < f.W-border; x++ {
+			v := f.Pix[y*f.W+x]
+			if v <= threshold {
+				continue
+			}
+			isMax := true
+			for j := -1; j <= 1 && isMax; j++ {
+				for i := -1; i <= 1; i++ {
+					if (i != 0 || j != 0) && f.Pix[(y+j)*f.W+x+i] >= v {
+						isMax = false
+						break
+					}
+				}
+			}
+			if isMax {
+				points = append(points, [2]int{x, y})
+			}
+		}
+	}
+	return points
+}
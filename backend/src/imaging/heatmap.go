@@ -0,0 +1,60 @@
+// blending a colormapped response map over the image it came from
+
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// HeatmapOptions configures Heatmap
+type HeatmapOptions struct {
+	Colormap Colormap
+	Alpha    float64 // opacity of the heatmap over the image, in [0, 1]
+	Log      bool    // show the top four decades below the peak logarithmically, as responses span many
+}
+
+func DefaultHeatmapOptions() HeatmapOptions {
+	return HeatmapOptions{Colormap: Viridis, Alpha: 0.6, Log: true}
+}
+
+// Heatmap colors response with the colormap and blends it over img. Negative responses, which
+// harris gives along edges, count as zero, and the strongest response maps to the top of the
+// colormap. The response map is laid over img from its top left corner.
+func Heatmap(img image.Image, response *Float, opts HeatmapOptions) *image.RGBA {
+	_, peak := response.MinMax()
+	if peak <= 0 {
+		peak = 1
+	}
+	scaled := NewFloat(response.W, response.H)
+	for i, v := range response.Pix {
+		v = math.Max(v, 0) / peak
+		if opts.Log {
+			v = math.Max(0, 1+math.Log10(math.Max(v, 1e-300))/4)
+		}
+		scaled.Pix[i] = v
+	}
+	alpha := math.Max(0, math.Min(1, opts.Alpha))
+
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			base := [3]float64{float64(r) / 257, float64(g) / 257, float64(bl) / 257}
+			if x >= response.W || y >= response.H {
+				out.SetRGBA(x, y, color.RGBA{clampUint8(base[0]), clampUint8(base[1]), clampUint8(base[2]), 255})
+				continue
+			}
+			c := opts.Colormap.At(scaled.At(x, y))
+			over := [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+			var px [3]uint8
+			for k := range px {
+				px[k] = clampUint8(base[k]*(1-alpha) + over[k]*alpha)
+			}
+			out.SetRGBA(x, y, color.RGBA{px[0], px[1], px[2], 255})
+		}
+	}
+	return out
+}
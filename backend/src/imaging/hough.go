@@ -0,0 +1,290 @@
+// standard and probabilistic hough line transforms, and corners from line intersections
+
+package imaging
+
+import (
+	"Backend/src/corner"
+	"Backend/src/geometry"
+	"image"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Line is an infinite line x*cos(theta) + y*sin(theta) = rho with the number of edge pixels on it
+type Line struct {
+	Rho   float64 `json:"rho"`
+	Theta float64 `json:"theta"`
+	Votes int     `json:"votes"`
+}
+
+// Segment is a finite line found by the probabilistic transform
+type Segment struct {
+	A geometry.Point `json:"a"`
+	B geometry.Point `json:"b"`
+}
+
+func (s Segment) Length() float64 {
+	return s.A.Dist(s.B)
+}
+
+type accumulator struct {
+	rhoRes   float64
+	numRho   int
+	numTheta int
+	cos, sin []float64
+	votes    []int
+}
+
+func newAccumulator(w, h int, rhoRes, thetaRes float64) *accumulator {
+	maxRho := math.Hypot(float64(w), float64(h))
+	a := &accumulator{
+		rhoRes:   rhoRes,
+		numRho:   2*int(math.Ceil(maxRho/rhoRes)) + 1,
+		numTheta: int(math.Round(math.Pi / thetaRes)),
+	}
+	a.cos = make([]float64, a.numTheta)
+	a.sin = make([]float64, a.numTheta)
+	for t := range a.cos {
+		theta := float64(t) * math.Pi / float64(a.numTheta)
+		a.cos[t], a.sin[t] = math.Cos(theta), math.Sin(theta)
+	}
+	a.votes = make([]int, a.numRho*a.numTheta)
+	return a
+}
+
+func (a *accumulator) rhoIndex(x, y, t int) int {
+	rho := float64(x)*a.cos[t] + float64(y)*a.sin[t]
+	return int(math.Round(rho/a.rhoRes)) + a.numRho/2
+}
+
+// vote adds delta for every theta bin of the pixel and returns the fullest bin it touched
+func (a *accumulator) vote(x, y, delta int) (int, int) {
+	best, bestT := 0, 0
+	for t := 0; t < a.numTheta; t++ {
+		i := t*a.numRho + a.rhoIndex(x, y, t)
+		a.votes[i] += delta
+		if a.votes[i] > best {
+			best, bestT = a.votes[i], t
+		}
+	}
+	return best, bestT
+}
+
+func (a *accumulator) line(r, t int) Line {
+	return Line{
+		Rho:   float64(r-a.numRho/2) * a.rhoRes,
+		Theta: float64(t) * math.Pi / float64(a.numTheta),
+		Votes: a.votes[t*a.numRho+r],
+	}
+}
+
+// HoughLines runs the standard hough transform on a binary edge map and returns the lines with at
+// least threshold votes that are local maxima of the accumulator, strongest first. maxLines of 0
+// returns all of them.
+func HoughLines(edges *image.Gray, rhoRes, thetaRes float64, threshold, maxLines int) []Line {
+	w, h := edges.Rect.Dx(), edges.Rect.Dy()
+	acc := newAccumulator(w, h, rhoRes, thetaRes)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if edges.Pix[y*edges.Stride+x] != 0 {
+				acc.vote(x, y, 1)
+			}
+		}
+	}
+
+	lines := make([]Line, 0)
+	for t := 0; t < acc.numTheta; t++ {
+		for r := 1; r < acc.numRho-1; r++ {
+			v := acc.votes[t*acc.numRho+r]
+			if v < threshold {
+				continue
+			}
+			isMax := true
+			for dt := -1; dt <= 1 && isMax; dt++ {
+				for dr := -1; dr <= 1; dr++ {
+					nt := t + dt
+					if nt < 0 || nt >= acc.numTheta || (dt == 0 && dr == 0) {
+						continue
+					}
+					n := acc.votes[nt*acc.numRho+r+dr]
+					// ties are broken towards the earlier bin so a plateau gives one line
+					if n > v || (n == v && (dt < 0 || (dt == 0 && dr < 0))) {
+						isMax = false
+						break
+					}
+				}
+			}
+			if isMax {
+				lines = append(lines, acc.line(r, t))
+			}
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].Votes > lines[j].Votes
+	})
+	if maxLines > 0 && len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+	return lines
+}
+
+// HoughLinesP runs the progressive probabilistic hough transform. Edge pixels vote in random order
+// and as soon as a bin reaches threshold the line is followed through the edge map, bridging gaps up
+// to maxGap pixels. Segments at least minLength long are returned and their pixels withdrawn from
+// the accumulator so they do not vote for other lines.
+func HoughLinesP(edges *image.Gray, rhoRes, thetaRes float64, threshold int, minLength, maxGap float64) []Segment {
+	w, h := edges.Rect.Dx(), edges.Rect.Dy()
+	acc := newAccumulator(w, h, rhoRes, thetaRes)
+	mask := make([]byte, w*h) // 1 pending, 2 voted
+	points := make([]int, 0)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if edges.Pix[y*edges.Stride+x] != 0 {
+				mask[y*w+x] = 1
+				points = append(points, y*w+x)
+			}
+		}
+	}
+	rng := rand.New(rand.NewSource(1))
+	rng.Shuffle(len(points), func(i, j int) { points[i], points[j] = points[j], points[i] })
+
+	segments := make([]Segment, 0)
+	for _, p := range points {
+		if mask[p] != 1 {
+			continue
+		}
+		x0, y0 := p%w, p/w
+		mask[p] = 2
+		votes, t := acc.vote(x0, y0, 1)
+		if votes < threshold {
+			continue
+		}
+
+		// walk both ways along the line direction, which is perpendicular to the normal (cos, sin)
+		dirX, dirY := -acc.sin[t], acc.cos[t]
+		var ends [2][2]int
+		for side, sign := range []float64{1, -1} {
+			ends[side] = [2]int{x0, y0}
+			gap := 0
+			for k := 1; ; k++ {
+				x := int(math.Round(float64(x0) + sign*float64(k)*dirX))
+				y := int(math.Round(float64(y0) + sign*float64(k)*dirY))
+				if x < 0 || y < 0 || x >= w || y >= h {
+					break
+				}
+				// the line direction is quantized, so also accept an edge one pixel off the path
+				if hit := nearbyEdge(mask, w, h, x, y, acc.cos[t], acc.sin[t]); hit >= 0 {
+					gap = 0
+					ends[side] = [2]int{hit % w, hit / w}
+				} else if gap++; float64(gap) > maxGap {
+					break
+				}
+			}
+		}
+
+		a := geometry.Point{X: float64(ends[0][0]), Y: float64(ends[0][1])}
+		b := geometry.Point{X: float64(ends[1][0]), Y: float64(ends[1][1])}
+		if a.Dist(b) < minLength {
+			continue
+		}
+
+		// clear the pixels of the segment, withdrawing the votes of those that already voted
+		steps := max(1, int(math.Ceil(a.Dist(b))))
+		for k := 0; k <= steps; k++ {
+			f := float64(k) / float64(steps)
+			x := int(math.Round(a.X + f*(b.X-a.X)))
+			y := int(math.Round(a.Y + f*(b.Y-a.Y)))
+			for i := nearbyEdge(mask, w, h, x, y, acc.cos[t], acc.sin[t]); i >= 0; i = nearbyEdge(mask, w, h, x, y, acc.cos[t], acc.sin[t]) {
+				if mask[i] == 2 {
+					acc.vote(i%w, i/w, -1)
+				}
+				mask[i] = 0
+			}
+		}
+		segments = append(segments, Segment{A: a, B: b})
+	}
+	return segments
+}
+
+// nearbyEdge returns the index of an edge pixel at (x, y) or one pixel away along the normal, or -1
+func nearbyEdge(mask []byte, w, h, x, y int, nx, ny float64) int {
+	for _, off := range []float64{0, 1, -1} {
+		px := int(math.Round(float64(x) + off*nx))
+		py := int(math.Round(float64(y) + off*ny))
+		if px >= 0 && py >= 0 && px < w && py < h && mask[py*w+px] != 0 {
+			return py*w + px
+		}
+	}
+	return -1
+}
+
+func (l Line) points() (geometry.Point, geometry.Point) {
+	c, s := math.Cos(l.Theta), math.Sin(l.Theta)
+	p := geometry.Point{X: l.Rho * c, Y: l.Rho * s}
+	return p, p.Add(geometry.Point{X: -s, Y: c})
+}
+
+// intersect returns the intersection of the lines through a1-a2 and b1-b2 as the parameters along each
+func intersect(a1, a2, b1, b2 geometry.Point) (float64, float64, bool) {
+	da, db := a2.Sub(a1), b2.Sub(b1)
+	den := da.Cross(db)
+	if math.Abs(den) < 1e-9 {
+		return 0, 0, false
+	}
+	diff := b1.Sub(a1)
+	return diff.Cross(db) / den, diff.Cross(da) / den, true
+}
+
+// LineIntersections returns a corner where two lines meet inside a w x h image at an angle of at
+// least minAngle radians, scored by the votes of the weaker line
+func LineIntersections(lines []Line, w, h int, minAngle float64) []corner.Corner {
+	corners := make([]corner.Corner, 0)
+	for i := range lines {
+		for j := i + 1; j < len(lines); j++ {
+			diff := math.Abs(lines[i].Theta - lines[j].Theta)
+			if math.Min(diff, math.Pi-diff) < minAngle {
+				continue
+			}
+			a1, a2 := lines[i].points()
+			b1, b2 := lines[j].points()
+			t, _, ok := intersect(a1, a2, b1, b2)
+			if !ok {
+				continue
+			}
+			p := a1.Add(a2.Sub(a1).Scale(t))
+			if p.X < 0 || p.Y < 0 || p.X > float64(w-1) || p.Y > float64(h-1) {
+				continue
+			}
+			corners = append(corners, corner.Corner{X: p.X, Y: p.Y, Score: float64(min(lines[i].Votes, lines[j].Votes))})
+		}
+	}
+	return corners
+}
+
+// SegmentIntersections returns a corner where two segments, each extended by at most maxExtend
+// pixels past its ends, cross at an angle of at least minAngle radians. The score is the length of
+// the shorter segment.
+func SegmentIntersections(segments []Segment, minAngle, maxExtend float64) []corner.Corner {
+	corners := make([]corner.Corner, 0)
+	for i := range segments {
+		for j := i + 1; j < len(segments); j++ {
+			a, b := segments[i], segments[j]
+			la, lb := a.Length(), b.Length()
+			if la == 0 || lb == 0 {
+				continue
+			}
+			cos := math.Abs(a.B.Sub(a.A).Dot(b.B.Sub(b.A))) / (la * lb)
+			if math.Acos(math.Min(cos, 1)) < minAngle {
+				continue
+			}
+			t, u, ok := intersect(a.A, a.B, b.A, b.B)
+			if !ok || t < -maxExtend/la || t > 1+maxExtend/la || u < -maxExtend/lb || u > 1+maxExtend/lb {
+				continue
+			}
+			p := a.A.Add(a.B.Sub(a.A).Scale(t))
+			corners = append(corners, corner.Corner{X: p.X, Y: p.Y, Score: math.Min(la, lb)})
+		}
+	}
+	return corners
+}
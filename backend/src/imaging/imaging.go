@@ -0,0 +1,174 @@
+// shared image helpers used by the detectors: decoding, grayscale conversion and filtering
+
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Float is a single channel image with float64 pixels, used for gradients and detector responses
+type Float struct {
+	W, H int
+	Pix  []float64
+}
+
+func NewFloat(w, h int) *Float {
+	return &Float{W: w, H: h, Pix: make([]float64, w*h)}
+}
+
+func (f *Float) At(x, y int) float64 {
+	return f.Pix[y*f.W+x]
+}
+
+func (f *Float) Set(x, y int, v float64) {
+	f.Pix[y*f.W+x] = v
+}
+
+// Clamped returns the pixel at (x, y), replicating the border for coordinates outside the image
+func (f *Float) Clamped(x, y int) float64 {
+	if x < 0 {
+		x = 0
+	} else if x >= f.W {
+		x = f.W - 1
+	}
+	if y < 0 {
+		y = 0
+	} else if y >= f.H {
+		y = f.H - 1
+	}
+	return f.Pix[y*f.W+x]
+}
+
+// Bilinear samples the image at a sub-pixel position
+func (f *Float) Bilinear(x, y float64) float64 {
+	x0 := int(math.Floor(x))
+	y0 := int(math.Floor(y))
+	ax := x - float64(x0)
+	ay := y - float64(y0)
+	top := f.Clamped(x0, y0)*(1-ax) + f.Clamped(x0+1, y0)*ax
+	bottom := f.Clamped(x0, y0+1)*(1-ax) + f.Clamped(x0+1, y0+1)*ax
+	return top*(1-ay) + bottom*ay
+}
+
+func (f *Float) Clone() *Float {
+	c := NewFloat(f.W, f.H)
+	copy(c.Pix, f.Pix)
+	return c
+}
+
+// MinMax returns the smallest and largest pixel values
+func (f *Float) MinMax() (float64, float64) {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range f.Pix {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return min, max
+}
+
+// Load decodes a png, jpeg or tiff image from disk. 16 bit png and tiff files decode to Gray16 or
+// RGBA64 images and float tiff files to Gray32, keeping their full precision.
+func Load(filePath string) (image.Image, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// Save encodes img as png, jpeg or tiff depending on the extension of filePath. Png and tiff keep 16
+// bit images, and tiff also keeps Gray32 as float samples.
+func Save(img image.Image, filePath string) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".png":
+		return png.Encode(f, img)
+	case ".jpg", ".jpeg":
+		return jpeg.Encode(f, img, nil)
+	case ".tif", ".tiff":
+		return EncodeTIFF(f, img)
+	default:
+		return fmt.Errorf("unsupported output format %q", filepath.Ext(filePath))
+	}
+}
+
+// ToFloat converts img to luminance in the range [0, 255], with the origin moved to (0, 0). Every
+// bit depth lands on the same scale, so thresholds mean the same fraction of full intensity for 8
+// and 16 bit images alike, while the extra precision survives as the fractional part.
+func ToFloat(img image.Image) *Float {
+	value := func(x, y int) float64 {
+		r, g, b, _ := img.At(x, y).RGBA()
+		// Weighted average for luminance perception, RGBA() returns 16 bit channels
+		return (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+	}
+	switch m := img.(type) {
+	case *image.Gray:
+		value = func(x, y int) float64 { return float64(m.GrayAt(x, y).Y) }
+	case *image.Gray16:
+		value = func(x, y int) float64 { return float64(m.Gray16At(x, y).Y) / 257 }
+	case *Gray32:
+		value = func(x, y int) float64 { return float64(m.Gray32At(x, y)) * 255 }
+	}
+	bounds := img.Bounds()
+	f := NewFloat(bounds.Dx(), bounds.Dy())
+	for y := 0; y < f.H; y++ {
+		for x := 0; x < f.W; x++ {
+			f.Pix[y*f.W+x] = value(bounds.Min.X+x, bounds.Min.Y+y)
+		}
+	}
+	return f
+}
+
+// ToGray converts a float image back to 8 bit, clamping values to [0, 255]
+func ToGray(f *Float) *image.Gray {
+	gray := image.NewGray(image.Rect(0, 0, f.W, f.H))
+	for i, v := range f.Pix {
+		gray.Pix[i] = clampUint8(v)
+	}
+	return gray
+}
+
+// Normalize stretches the values of f to [0, 255] so responses and gradients can be viewed
+func Normalize(f *Float) *image.Gray {
+	min, max := f.MinMax()
+	gray := image.NewGray(image.Rect(0, 0, f.W, f.H))
+	if max <= min {
+		return gray
+	}
+	for i, v := range f.Pix {
+		gray.Pix[i] = clampUint8(255 * (v - min) / (max - min))
+	}
+	return gray
+}
+
+func clampUint8(v float64) uint8 {
+	if v < 0 {
+		return 0
+	} else if v > 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// GrayColor is a helper for callers building images pixel by pixel
+func GrayColor(v float64) color.Gray {
+	return color.Gray{clampUint8(v)}
+}
@@ -0,0 +1,80 @@
+// edge and line detection entry point used by the http server
+
+package imaging
+
+import (
+	"Backend/src/corner"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+type LineOptions struct {
+	Sigma         float64 // canny smoothing
+	Low, High     float64 // canny hysteresis thresholds on the sobel magnitude
+	Probabilistic bool    // find segments instead of infinite lines
+	Threshold     int     // minimum hough votes
+	MaxLines      int     // strongest lines kept by the standard transform
+	MinLength     float64 // shortest segment for the probabilistic transform
+	MaxGap        float64 // largest gap bridged inside a segment
+	MaxExtend     float64 // how far segments are extended to meet at a corner
+}
+
+func DefaultLineOptions() LineOptions {
+	return LineOptions{
+		Sigma:     1.4,
+		Low:       100,
+		High:      250,
+		Threshold: 80,
+		MaxLines:  30,
+		MinLength: 40,
+		MaxGap:    5,
+		MaxExtend: 20,
+	}
+}
+
+// FindLines returns the canny edges of img, a copy of img with the hough lines (green) and their
+// intersections (red) drawn over it, and the intersections
+func FindLines(img image.Image, opts LineOptions) (*image.Gray, *image.RGBA, []corner.Corner) {
+	edges := Canny(ToFloat(img), opts.Sigma, opts.Low, opts.High)
+
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	lineColor := color.RGBA{0, 255, 0, 255}
+	minAngle := math.Pi / 6
+
+	var corners []corner.Corner
+	if opts.Probabilistic {
+		segments := HoughLinesP(edges, 1, math.Pi/180, opts.Threshold, opts.MinLength, opts.MaxGap)
+		for _, s := range segments {
+			DrawLine(rgba, s.A, s.B, lineColor)
+		}
+		corners = SegmentIntersections(segments, minAngle, opts.MaxExtend)
+	} else {
+		lines := HoughLines(edges, 1, math.Pi/180, opts.Threshold, opts.MaxLines)
+		for _, l := range lines {
+			DrawInfiniteLine(rgba, l, lineColor)
+		}
+		corners = LineIntersections(lines, bounds.Dx(), bounds.Dy(), minAngle)
+	}
+	return edges, corner.Draw(rgba, corners), corners
+}
+
+// DetectLines saves the canny edges of the image at inputPath to edgesPath and the image with the
+// hough lines (green) and their intersections (red) to linesPath, returning the intersections
+func DetectLines(inputPath, edgesPath, linesPath string, opts LineOptions) ([]corner.Corner, error) {
+	img, err := Load(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	edges, drawn, corners := FindLines(img, opts)
+	if err := Save(edges, edgesPath); err != nil {
+		return nil, err
+	}
+	if err := Save(drawn, linesPath); err != nil {
+		return nil, err
+	}
+	return corners, nil
+}
@@ -0,0 +1,110 @@
+// named intermediate images collected while a detector runs, for debugging
+
+package imaging
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var ErrMapFormat = errors.New("unknown map format")
+
+// Maps collects intermediate images by name in the order they were produced. A nil *Maps ignores
+// everything added to it, so detectors can report unconditionally.
+type Maps struct {
+	Names  []string
+	Images map[string]*Float
+}
+
+func NewMaps() *Maps {
+	return &Maps{Images: make(map[string]*Float)}
+}
+
+// Enabled reports whether maps are being collected, letting callers skip building costly ones
+func (m *Maps) Enabled() bool {
+	return m != nil
+}
+
+// Add records a map, replacing an earlier one of the same name
+func (m *Maps) Add(name string, f *Float) {
+	if m == nil {
+		return
+	}
+	if _, ok := m.Images[name]; !ok {
+		m.Names = append(m.Names, name)
+	}
+	m.Images[name] = f.Clone()
+}
+
+// AddRows records a map held as rows of values, zero padding rows shorter than w to a w×h image
+func (m *Maps) AddRows(name string, rows [][]float64, w, h int) {
+	if m == nil {
+		return
+	}
+	f := NewFloat(w, h)
+	for y := 0; y < h && y < len(rows); y++ {
+		copy(f.Pix[y*w:(y+1)*w], rows[y])
+	}
+	m.Add(name, f)
+}
+
+// Encode writes one map as a png stretched to the full gray range, or as a float tiff holding the
+// raw values
+func (m *Maps) Encode(w io.Writer, name, format string) error {
+	f, ok := m.Images[name]
+	if !ok {
+		return fmt.Errorf("no map named %q", name)
+	}
+	switch format {
+	case "png":
+		return png.Encode(w, Normalize(f))
+	case "tiff":
+		raw := NewGray32(image.Rect(0, 0, f.W, f.H))
+		for i, v := range f.Pix {
+			raw.Pix[i] = float32(v)
+		}
+		return EncodeTIFF(w, raw)
+	}
+	return fmt.Errorf("%w %q", ErrMapFormat, format)
+}
+
+// Save writes every map into dir as <name>.png or <name>.tiff and returns the paths written
+func (m *Maps) Save(dir, format string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(m.Names))
+	for _, name := range m.Names {
+		path := filepath.Join(dir, name+"."+format)
+		var buf bytes.Buffer
+		if err := m.Encode(&buf, name, format); err != nil {
+			return paths, err
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// WriteZip adds every map to a zip archive as <name>.png or <name>.tiff
+func (m *Maps) WriteZip(zw *zip.Writer, format string) error {
+	for _, name := range m.Names {
+		w, err := zw.Create(name + "." + format)
+		if err != nil {
+			return err
+		}
+		if err := m.Encode(w, name, format); err != nil {
+			return err
+		}
+	}
+	return nil
+}
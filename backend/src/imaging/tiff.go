@@ -0,0 +1,515 @@
+// a small baseline tiff codec for 8 and 16 bit gray or rgb and 32 bit float gray images
+
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+var (
+	ErrTIFF            = errors.New("invalid tiff")
+	ErrTIFFUnsupported = errors.New("unsupported tiff")
+)
+
+const (
+	tagWidth        = 256
+	tagHeight       = 257
+	tagBitsPerPixel = 258
+	tagCompression  = 259
+	tagPhotometric  = 262
+	tagStripOffsets = 273
+	tagSamples      = 277
+	tagRowsPerStrip = 278
+	tagStripCounts  = 279
+	tagPlanar       = 284
+	tagPredictor    = 317
+	tagExtraSamples = 338
+	tagSampleFormat = 339
+)
+
+func init() {
+	image.RegisterFormat("tiff", "II*\x00", DecodeTIFF, DecodeTIFFConfig)
+	image.RegisterFormat("tiff", "MM\x00*", DecodeTIFF, DecodeTIFFConfig)
+}
+
+// tiffHeader is the first image file directory of a tiff, with every value widened to uint32
+type tiffHeader struct {
+	order  binary.ByteOrder
+	fields map[uint16][]uint32
+}
+
+// field returns the first value of a tag, or def when the tag is absent
+func (t *tiffHeader) field(tag uint16, def uint32) uint32 {
+	if v := t.fields[tag]; len(v) > 0 {
+		return v[0]
+	}
+	return def
+}
+
+func readTIFFHeader(data []byte) (*tiffHeader, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: short header", ErrTIFF)
+	}
+	t := &tiffHeader{fields: make(map[uint16][]uint32)}
+	switch string(data[:4]) {
+	case "II*\x00":
+		t.order = binary.LittleEndian
+	case "MM\x00*":
+		t.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("%w: bad magic", ErrTIFF)
+	}
+	offset := int(t.order.Uint32(data[4:]))
+	if offset+2 > len(data) {
+		return nil, fmt.Errorf("%w: directory out of range", ErrTIFF)
+	}
+	count := int(t.order.Uint16(data[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + 12*i
+		if entry+12 > len(data) {
+			return nil, fmt.Errorf("%w: directory out of range", ErrTIFF)
+		}
+		tag := t.order.Uint16(data[entry:])
+		kind := t.order.Uint16(data[entry+2:])
+		n := int(t.order.Uint32(data[entry+4:]))
+		size := map[uint16]int{1: 1, 3: 2, 4: 4}[kind]
+		if size == 0 {
+			continue // rationals, strings and the like carry nothing the decoder needs
+		}
+		values := data[entry+8 : entry+12]
+		if size*n > 4 {
+			at := int(t.order.Uint32(values))
+			if at < 0 || at+size*n > len(data) {
+				return nil, fmt.Errorf("%w: tag %d out of range", ErrTIFF, tag)
+			}
+			values = data[at : at+size*n]
+		}
+		field := make([]uint32, n)
+		for j := range field {
+			switch size {
+			case 1:
+				field[j] = uint32(values[j])
+			case 2:
+				field[j] = uint32(t.order.Uint16(values[2*j:]))
+			case 4:
+				field[j] = t.order.Uint32(values[4*j:])
+			}
+		}
+		t.fields[tag] = field
+	}
+	return t, nil
+}
+
+// DecodeTIFFConfig reads the dimensions and color model of a tiff without decoding the pixels
+func DecodeTIFFConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	t, err := readTIFFHeader(data)
+	if err != nil {
+		return image.Config{}, err
+	}
+	model := color.Model(color.RGBAModel)
+	bits, samples := t.field(tagBitsPerPixel, 1), t.field(tagSamples, 1)
+	switch {
+	case samples == 1 && bits == 8:
+		model = color.GrayModel
+	case samples == 1:
+		model = color.Gray16Model
+	case bits == 16:
+		model = color.RGBA64Model
+	}
+	return image.Config{ColorModel: model, Width: int(t.field(tagWidth, 0)), Height: int(t.field(tagHeight, 0))}, nil
+}
+
+// DecodeTIFF decodes the first image of an uncompressed, deflate, lzw or packbits compressed tiff
+// stored in strips. 8 bit data decodes to Gray or RGBA, 16 bit to Gray16 or RGBA64 and 32 bit float
+// gray to Gray32.
+func DecodeTIFF(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	t, err := readTIFFHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	w, h := int(t.field(tagWidth, 0)), int(t.field(tagHeight, 0))
+	bits := int(t.field(tagBitsPerPixel, 1))
+	samples := int(t.field(tagSamples, 1))
+	format := t.field(tagSampleFormat, 1)
+	photometric := t.field(tagPhotometric, 1)
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("%w: empty image", ErrTIFF)
+	}
+	if t.field(tagPlanar, 1) != 1 {
+		return nil, fmt.Errorf("%w: planar configuration", ErrTIFFUnsupported)
+	}
+	switch {
+	case format == 1 && (bits == 8 || bits == 16) && (samples == 1 || samples == 3 || samples == 4):
+	case format == 3 && bits == 32 && samples == 1:
+	default:
+		return nil, fmt.Errorf("%w: %d samples of %d bits in format %d", ErrTIFFUnsupported, samples, bits, format)
+	}
+	if samples > 1 && photometric != 2 {
+		return nil, fmt.Errorf("%w: photometric interpretation %d", ErrTIFFUnsupported, photometric)
+	}
+
+	rowBytes := w * samples * bits / 8
+	pix, err := readStrips(t, data, h, rowBytes)
+	if err != nil {
+		return nil, err
+	}
+	switch t.field(tagPredictor, 1) {
+	case 1:
+	case 2:
+		if format != 1 {
+			return nil, fmt.Errorf("%w: predictor on float samples", ErrTIFFUnsupported)
+		}
+		undoPredictor(pix, t.order, w, h, samples, bits)
+	default:
+		return nil, fmt.Errorf("%w: predictor %d", ErrTIFFUnsupported, t.field(tagPredictor, 1))
+	}
+
+	rect := image.Rect(0, 0, w, h)
+	invert := photometric == 0 // WhiteIsZero
+	switch {
+	case format == 3:
+		img := NewGray32(rect)
+		for i := range img.Pix {
+			img.Pix[i] = math.Float32frombits(t.order.Uint32(pix[4*i:]))
+		}
+		return img, nil
+	case samples == 1 && bits == 8:
+		img := image.NewGray(rect)
+		copy(img.Pix, pix)
+		if invert {
+			for i, v := range img.Pix {
+				img.Pix[i] = 255 - v
+			}
+		}
+		return img, nil
+	case samples == 1:
+		img := image.NewGray16(rect)
+		for i := 0; i < w*h; i++ {
+			v := t.order.Uint16(pix[2*i:])
+			if invert {
+				v = 0xffff - v
+			}
+			img.Pix[2*i], img.Pix[2*i+1] = uint8(v>>8), uint8(v)
+		}
+		return img, nil
+	case bits == 8:
+		img := image.NewNRGBA(rect)
+		for i := 0; i < w*h; i++ {
+			copy(img.Pix[4*i:4*i+3], pix[samples*i:samples*i+3])
+			img.Pix[4*i+3] = 255
+			if samples == 4 {
+				img.Pix[4*i+3] = pix[samples*i+3]
+			}
+		}
+		return img, nil
+	default:
+		img := image.NewNRGBA64(rect)
+		for i := 0; i < w*h; i++ {
+			for c := 0; c < 4; c++ {
+				v := uint16(0xffff)
+				if c < samples {
+					v = t.order.Uint16(pix[2*(samples*i+c):])
+				}
+				img.Pix[8*i+2*c], img.Pix[8*i+2*c+1] = uint8(v>>8), uint8(v)
+			}
+		}
+		return img, nil
+	}
+}
+
+// maxExpansion bounds how many pixel bytes one byte of data decodes to under each supported
+// compression: deflate tops out near 1032:1, lzw emits at most 4096 bytes per 9 bit code and
+// packbits 128 bytes per 2
+var maxExpansion = map[uint32]int{1: 1, 5: 3641, 8: 1032, 32946: 1032, 32773: 64}
+
+// readStrips decompresses and concatenates the strips holding h rows of rowBytes each
+func readStrips(t *tiffHeader, data []byte, h, rowBytes int) ([]byte, error) {
+	offsets, counts := t.fields[tagStripOffsets], t.fields[tagStripCounts]
+	if len(offsets) == 0 || len(offsets) != len(counts) {
+		return nil, fmt.Errorf("%w: missing strips (tiled tiffs are not supported)", ErrTIFF)
+	}
+	compression := t.field(tagCompression, 1)
+	expansion, ok := maxExpansion[compression]
+	if !ok {
+		return nil, fmt.Errorf("%w: compression %d", ErrTIFFUnsupported, compression)
+	}
+	// corrupt dimensions would otherwise allocate far more than the file can hold
+	if h > len(data)*expansion/rowBytes {
+		return nil, fmt.Errorf("%w: %d rows of %d bytes cannot be decoded from %d bytes", ErrTIFF, h, rowBytes, len(data))
+	}
+	rowsPerStrip := int(t.field(tagRowsPerStrip, uint32(h)))
+	if rowsPerStrip <= 0 || rowsPerStrip > h {
+		rowsPerStrip = h // 2^32-1 is the usual way of saying the whole image is one strip
+	}
+	if strips := (h + rowsPerStrip - 1) / rowsPerStrip; len(offsets) != strips {
+		return nil, fmt.Errorf("%w: %d strips, %d rows of %d need %d", ErrTIFF, len(offsets), h, rowsPerStrip, strips)
+	}
+	pix := make([]byte, 0, h*rowBytes)
+	for i, offset := range offsets {
+		end := int(offset) + int(counts[i])
+		if end > len(data) {
+			return nil, fmt.Errorf("%w: strip out of range", ErrTIFF)
+		}
+		raw := data[offset:end]
+		want := min(rowsPerStrip, h-i*rowsPerStrip) * rowBytes
+		var strip []byte
+		var err error
+		switch compression {
+		case 1:
+			strip = raw
+		case 8, 32946:
+			var zr io.ReadCloser
+			if zr, err = zlib.NewReader(bytes.NewReader(raw)); err == nil {
+				// a few kilobytes of deflate can expand to gigabytes, only the strip's rows are read
+				strip, err = io.ReadAll(io.LimitReader(zr, int64(want)+1))
+			}
+		case 5:
+			strip, err = decodeLZW(raw, want)
+		case 32773:
+			strip, err = decodePackBits(raw, want)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: strip %d: %v", ErrTIFF, i, err)
+		}
+		if len(strip) < want {
+			return nil, fmt.Errorf("%w: strip %d is short", ErrTIFF, i)
+		}
+		pix = append(pix, strip[:want]...)
+	}
+	if len(pix) < h*rowBytes {
+		return nil, fmt.Errorf("%w: missing rows", ErrTIFF)
+	}
+	return pix, nil
+}
+
+// undoPredictor integrates the horizontal differences the tiff predictor 2 stores along each row
+func undoPredictor(pix []byte, order binary.ByteOrder, w, h, samples, bits int) {
+	for y := 0; y < h; y++ {
+		if bits == 8 {
+			row := pix[y*w*samples : (y+1)*w*samples]
+			for i := samples; i < len(row); i++ {
+				row[i] += row[i-samples]
+			}
+			continue
+		}
+		row := pix[y*w*samples*2 : (y+1)*w*samples*2]
+		for i := samples; i < w*samples; i++ {
+			order.PutUint16(row[2*i:], order.Uint16(row[2*i:])+order.Uint16(row[2*(i-samples):]))
+		}
+	}
+}
+
+// decodePackBits expands the run length encoding of tiff compression 32773
+func decodePackBits(src []byte, want int) ([]byte, error) {
+	out := make([]byte, 0, want)
+	for i := 0; i < len(src) && len(out) < want; {
+		n := int(int8(src[i]))
+		i++
+		switch {
+		case n >= 0:
+			if i+n+1 > len(src) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			out = append(out, src[i:i+n+1]...)
+			i += n + 1
+		case n != -128:
+			if i >= len(src) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			out = append(out, bytes.Repeat(src[i:i+1], 1-n)...)
+			i++
+		}
+	}
+	return out, nil
+}
+
+// decodeLZW expands tiff lzw data, which is msb first and widens its codes one entry early, unlike
+// the variant compress/lzw implements. It stops once want bytes are decoded.
+func decodeLZW(src []byte, want int) ([]byte, error) {
+	const clear, eoi = 256, 257
+	out := make([]byte, 0, want)
+	table := make([][]byte, 258, 4096)
+	reset := func() {
+		table = table[:258]
+		for i := 0; i < 256; i++ {
+			table[i] = []byte{byte(i)}
+		}
+	}
+	reset()
+	width, bitPos := 9, 0
+	var prev []byte
+	for {
+		if bitPos+width > 8*len(src) {
+			break
+		}
+		code := 0
+		for b := 0; b < width; b++ {
+			bit := src[(bitPos+b)/8] >> (7 - uint((bitPos+b)%8)) & 1
+			code = code<<1 | int(bit)
+		}
+		bitPos += width
+		switch {
+		case code == clear:
+			reset()
+			width, prev = 9, nil
+			continue
+		case code == eoi:
+			return out, nil
+		}
+		var entry []byte
+		switch {
+		case code < len(table):
+			entry = table[code]
+		case code == len(table) && prev != nil:
+			entry = append(append([]byte(nil), prev...), prev[0])
+		default:
+			return nil, fmt.Errorf("lzw code %d out of range", code)
+		}
+		out = append(out, entry...)
+		if len(out) >= want {
+			return out, nil
+		}
+		if prev != nil && len(table) < 4096 {
+			table = append(table, append(append([]byte(nil), prev...), entry[0]))
+		}
+		prev = entry
+		if len(table)+1 >= 1<<width && width < 12 {
+			width++
+		}
+	}
+	return out, nil
+}
+
+// EncodeTIFF writes img as an uncompressed little endian tiff in a single strip. Gray and Gray16
+// keep their depth, Gray32 is stored as 32 bit float samples, 16 bit color images as 16 bit rgb and
+// everything else as 8 bit rgb.
+func EncodeTIFF(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	samples, bits, format, photometric := 3, 8, 1, 2
+	var pix []byte
+	le := binary.LittleEndian
+	switch m := img.(type) {
+	case *image.Gray:
+		samples, photometric = 1, 1
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				pix = append(pix, m.GrayAt(x, y).Y)
+			}
+		}
+	case *image.Gray16:
+		samples, bits, photometric = 1, 16, 1
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				pix = le.AppendUint16(pix, m.Gray16At(x, y).Y)
+			}
+		}
+	case *Gray32:
+		samples, bits, format, photometric = 1, 32, 3, 1
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				pix = le.AppendUint32(pix, math.Float32bits(m.Gray32At(x, y)))
+			}
+		}
+	default:
+		if BitDepth(img) == 16 {
+			bits = 16
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+				for _, v := range []uint16{c.R, c.G, c.B} {
+					if bits == 16 {
+						pix = le.AppendUint16(pix, v)
+					} else {
+						pix = append(pix, uint8(v>>8))
+					}
+				}
+			}
+		}
+	}
+
+	type entry struct {
+		tag, kind uint16
+		values    []uint32
+	}
+	bitsPerSample := make([]uint32, samples)
+	formats := make([]uint32, samples)
+	for i := range bitsPerSample {
+		bitsPerSample[i], formats[i] = uint32(bits), uint32(format)
+	}
+	entries := []entry{
+		{tagWidth, 4, []uint32{uint32(width)}},
+		{tagHeight, 4, []uint32{uint32(height)}},
+		{tagBitsPerPixel, 3, bitsPerSample},
+		{tagCompression, 3, []uint32{1}},
+		{tagPhotometric, 3, []uint32{uint32(photometric)}},
+		{tagStripOffsets, 4, []uint32{0}}, // patched once the layout is known
+		{tagSamples, 3, []uint32{uint32(samples)}},
+		{tagRowsPerStrip, 4, []uint32{uint32(height)}},
+		{tagStripCounts, 4, []uint32{uint32(len(pix))}},
+		{tagPlanar, 3, []uint32{1}},
+		{tagSampleFormat, 3, formats},
+	}
+	// header, directory, then the values that do not fit in their entry, then the pixels
+	ifdSize := 2 + 12*len(entries) + 4
+	extraAt := 8 + ifdSize
+	var extra []byte
+	var ifd []byte
+	ifd = le.AppendUint16(ifd, uint16(len(entries)))
+	for _, e := range entries {
+		size := 2
+		if e.kind == 4 {
+			size = 4
+		}
+		var values []byte
+		for _, v := range e.values {
+			if size == 2 {
+				values = le.AppendUint16(values, uint16(v))
+			} else {
+				values = le.AppendUint32(values, v)
+			}
+		}
+		ifd = le.AppendUint16(ifd, e.tag)
+		ifd = le.AppendUint16(ifd, e.kind)
+		ifd = le.AppendUint32(ifd, uint32(len(e.values)))
+		if len(values) <= 4 {
+			ifd = append(ifd, append(values, make([]byte, 4-len(values))...)...)
+		} else {
+			ifd = le.AppendUint32(ifd, uint32(extraAt+len(extra)))
+			extra = append(extra, values...)
+		}
+	}
+	ifd = le.AppendUint32(ifd, 0) // no further directories
+	pixAt := extraAt + len(extra)
+	for i, e := range entries {
+		if e.tag == tagStripOffsets {
+			le.PutUint32(ifd[2+12*i+8:], uint32(pixAt))
+		}
+	}
+
+	header := []byte("II*\x00")
+	header = le.AppendUint32(header, 8)
+	for _, part := range [][]byte{header, ifd, extra, pix} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
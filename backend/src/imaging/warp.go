@@ -0,0 +1,95 @@
+// resampling helpers for geometric transforms
+
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Warp builds a width x height image where every output pixel (x, y) takes the color of img at
+// source(x, y), sampled bilinearly and black outside the input. source works in coordinates
+// relative to the image origin.
+func Warp(img image.Image, width, height int, source func(x, y float64) (float64, float64)) *image.RGBA {
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx, sy := source(float64(x), float64(y))
+			out.SetRGBA(x, y, Sample(img, sx, sy))
+		}
+	}
+	return out
+}
+
+// Sample interpolates img at a sub-pixel position measured from the image origin, black outside
+func Sample(img image.Image, x, y float64) color.RGBA {
+	bounds := img.Bounds()
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	if x0 < 0 || y0 < 0 || x0+1 >= bounds.Dx() || y0+1 >= bounds.Dy() {
+		return color.RGBA{0, 0, 0, 255}
+	}
+	ax, ay := x-float64(x0), y-float64(y0)
+	var acc [4]float64
+	weights := [4]float64{(1 - ax) * (1 - ay), ax * (1 - ay), (1 - ax) * ay, ax * ay}
+	offsets := [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}}
+	for i, o := range offsets {
+		r, g, b, a := img.At(bounds.Min.X+x0+o[0], bounds.Min.Y+y0+o[1]).RGBA()
+		acc[0] += weights[i] * float64(r)
+		acc[1] += weights[i] * float64(g)
+		acc[2] += weights[i] * float64(b)
+		acc[3] += weights[i] * float64(a)
+	}
+	return color.RGBA{uint8(acc[0] / 257), uint8(acc[1] / 257), uint8(acc[2] / 257), uint8(acc[3] / 257)}
+}
+
+// Downsample shrinks f by an integer factor, averaging each factor x factor block
+func Downsample(f *Float, factor int) *Float {
+	if factor <= 1 {
+		return f.Clone()
+	}
+	out := NewFloat(f.W/factor, f.H/factor)
+	area := float64(factor * factor)
+	for y := 0; y < out.H; y++ {
+		for x := 0; x < out.W; x++ {
+			sum := 0.0
+			for j := 0; j < factor; j++ {
+				row := (y*factor + j) * f.W
+				for i := 0; i < factor; i++ {
+					sum += f.Pix[row+x*factor+i]
+				}
+			}
+			out.Pix[y*out.W+x] = sum / area
+		}
+	}
+	return out
+}
+
+// Otsu returns the threshold on [0, 255] that maximizes the between class variance of f
+func Otsu(f *Float) float64 {
+	var hist [256]float64
+	for _, v := range f.Pix {
+		hist[clampUint8(v)]++
+	}
+	total := float64(len(f.Pix))
+	sumAll := 0.0
+	for i, h := range hist {
+		sumAll += float64(i) * h
+	}
+	best, bestVar := 0, -1.0
+	weight, sum := 0.0, 0.0
+	for t, h := range hist {
+		weight += h
+		sum += float64(t) * h
+		if weight == 0 || weight == total {
+			continue
+		}
+		m0 := sum / weight
+		m1 := (sumAll - sum) / (total - weight)
+		v := weight * (total - weight) * (m0 - m1) * (m0 - m1)
+		if v > bestVar {
+			best, bestVar = t, v
+		}
+	}
+	return float64(best) + 0.5
+}
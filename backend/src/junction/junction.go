@@ -0,0 +1,144 @@
+// junction classification of detected corners
+//
+// Edges leaving a corner show up as directions where the image gradient, sampled along a ray from
+// the corner, is perpendicular to the ray. The angular profile of that perpendicular gradient energy
+// is computed on an annulus around the corner and its peaks are the dominant edge directions. The
+// number of edges and whether any two of them are collinear decide the junction type.
+
+package junction
+
+import (
+	"Backend/src/corner"
+	"Backend/src/imaging"
+	"math"
+	"sort"
+)
+
+type Options struct {
+	Radius      float64 // outer radius of the sampled annulus, scaled by 3 sigma for scale adapted corners
+	Bins        int     // angular resolution of the profile
+	MinStrength float64 // fraction of the strongest direction an edge must reach
+	MinContrast float64 // minimum mean perpendicular gradient (sobel units) of an edge
+	Collinear   float64 // tolerance in degrees for two edges to count as one straight line
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Radius:      7,
+		Bins:        72,
+		MinStrength: 0.3,
+		MinContrast: 20,
+		Collinear:   20,
+	}
+}
+
+// Gradients returns the derivative images Classify samples, sobel on a lightly smoothed copy
+func Gradients(gray *imaging.Float) (*imaging.Float, *imaging.Float) {
+	return imaging.Sobel(imaging.Gaussian(gray, 1))
+}
+
+// profile returns the mean gradient component perpendicular to rays leaving (x, y) for every bin
+func profile(dx, dy *imaging.Float, x, y, radius float64, bins int) []float64 {
+	inner := math.Max(2, radius/3)
+	energy := make([]float64, bins)
+	for b := range energy {
+		theta := 2 * math.Pi * float64(b) / float64(bins)
+		c, s := math.Cos(theta), math.Sin(theta)
+		sum, count := 0.0, 0
+		for r := inner; r <= radius; r += 0.5 {
+			px, py := x+r*c, y+r*s
+			sum += math.Abs(-s*dx.Bilinear(px, py) + c*dy.Bilinear(px, py))
+			count++
+		}
+		energy[b] = sum / float64(count)
+	}
+	// light circular smoothing so one edge gives one peak
+	smooth := make([]float64, bins)
+	for b := range energy {
+		smooth[b] = (energy[(b-1+bins)%bins] + 2*energy[b] + energy[(b+1)%bins]) / 4
+	}
+	return smooth
+}
+
+// angleDiff returns the unsigned difference of two directions in degrees, in [0, 180]
+func angleDiff(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 360)
+	return math.Min(d, 360-d)
+}
+
+// Classify finds the dominant edge directions around (x, y) and the junction type they form
+func Classify(dx, dy *imaging.Float, x, y, radius float64, opts Options) corner.Junction {
+	bins := opts.Bins
+	energy := profile(dx, dy, x, y, radius, bins)
+	strongest := 0.0
+	for _, e := range energy {
+		strongest = math.Max(strongest, e)
+	}
+
+	// peaks must dominate a window of +-15 degrees
+	window := max(1, bins/24)
+	angles := make([]float64, 0)
+	for b, e := range energy {
+		if e < opts.MinStrength*strongest || e < opts.MinContrast {
+			continue
+		}
+		isPeak := true
+		for d := -window; d <= window && isPeak; d++ {
+			n := energy[(b+d+bins)%bins]
+			if d != 0 && (n > e || (n == e && d < 0)) {
+				isPeak = false
+			}
+		}
+		if !isPeak {
+			continue
+		}
+		// parabolic interpolation of the peak position between bins
+		l, r := energy[(b-1+bins)%bins], energy[(b+1)%bins]
+		offset := 0.0
+		if den := l - 2*e + r; den != 0 {
+			offset = 0.5 * (l - r) / den
+		}
+		angle := math.Mod((float64(b)+offset)*360/float64(bins)+360, 360)
+		angles = append(angles, angle)
+	}
+	sort.Float64s(angles)
+
+	j := corner.Junction{Type: corner.JunctionAmbiguous, Angles: angles}
+	collinear := 0
+	for a := range angles {
+		for b := a + 1; b < len(angles); b++ {
+			if angleDiff(angles[a], angles[b]) > 180-opts.Collinear {
+				collinear++
+			}
+		}
+	}
+	switch len(angles) {
+	case 2:
+		// two opposite directions are a straight edge, not a corner
+		if collinear == 0 {
+			j.Type = corner.JunctionL
+		}
+	case 3:
+		if collinear > 0 {
+			j.Type = corner.JunctionT
+		} else {
+			j.Type = corner.JunctionY
+		}
+	case 4:
+		j.Type = corner.JunctionX
+	}
+	return j
+}
+
+// Annotate classifies every corner of any detector in place
+func Annotate(gray *imaging.Float, corners []corner.Corner, opts Options) {
+	dx, dy := Gradients(gray)
+	for i, c := range corners {
+		radius := opts.Radius
+		if c.Scale > 0 {
+			radius = 3 * c.Scale
+		}
+		j := Classify(dx, dy, c.X, c.Y, radius, opts)
+		corners[i].Junction = &j
+	}
+}
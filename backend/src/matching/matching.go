@@ -0,0 +1,112 @@
+// matches corners between two images by comparing their descriptor patches
+
+package matching
+
+import (
+	"Backend/src/descriptor"
+	"Backend/src/geometry"
+	"Backend/src/imaging"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// Match pairs patch A of the first image with patch B of the second
+type Match struct {
+	A        int     `json:"a"`
+	B        int     `json:"b"`
+	Distance float64 `json:"distance"` // root mean square difference of the patches
+}
+
+type Options struct {
+	Ratio       float64 // keep a match only when it beats the second best by this factor (lowe's test), 0 or 1 to disable
+	CrossCheck  bool    // keep a match only when each patch is the other's best
+	MaxDistance float64 // largest distance kept, 0 for any
+}
+
+func DefaultOptions() Options {
+	return Options{Ratio: 0.8, CrossCheck: true}
+}
+
+// distance returns the root mean square difference of two patches of the same size
+func distance(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		d := float64(a[i] - b[i])
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(a)))
+}
+
+// best returns the index and distance of the nearest and the distance of the second nearest patch
+func best(p descriptor.Patch, candidates []descriptor.Patch) (int, float64, float64) {
+	index, first, second := -1, math.Inf(1), math.Inf(1)
+	for j, q := range candidates {
+		d := distance(p.Data, q.Data)
+		if d < first {
+			index, first, second = j, d, first
+		} else if d < second {
+			second = d
+		}
+	}
+	return index, first, second
+}
+
+// BruteForce finds for every patch of a its nearest patch of b by brute force, filtering with the ratio
+// test, the cross check and the distance limit, and returns the matches closest first. Patches
+// must all have the same size, as Describe gives them.
+func BruteForce(a, b []descriptor.Patch, opts Options) []Match {
+	var reverse []int
+	if opts.CrossCheck {
+		reverse = make([]int, len(b))
+		for j, q := range b {
+			reverse[j], _, _ = best(q, a)
+		}
+	}
+	matches := make([]Match, 0)
+	for i, p := range a {
+		j, first, second := best(p, b)
+		if j < 0 {
+			continue
+		}
+		if opts.Ratio > 0 && opts.Ratio < 1 && first > opts.Ratio*second {
+			continue
+		}
+		if opts.CrossCheck && reverse[j] != i {
+			continue
+		}
+		if opts.MaxDistance > 0 && first > opts.MaxDistance {
+			continue
+		}
+		matches = append(matches, Match{A: i, B: j, Distance: first})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Distance < matches[j].Distance
+	})
+	return matches
+}
+
+// Draw places the two images side by side and joins every matched pair of corners with a line,
+// colored from green for the closest matches to red for the farthest
+func Draw(imgA, imgB image.Image, a, b []descriptor.Patch, matches []Match) *image.RGBA {
+	ba, bb := imgA.Bounds(), imgB.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, ba.Dx()+bb.Dx(), max(ba.Dy(), bb.Dy())))
+	draw.Draw(out, image.Rect(0, 0, ba.Dx(), ba.Dy()), imgA, ba.Min, draw.Src)
+	draw.Draw(out, image.Rect(ba.Dx(), 0, out.Bounds().Dx(), bb.Dy()), imgB, bb.Min, draw.Src)
+	worst := 0.0
+	for _, m := range matches {
+		worst = math.Max(worst, m.Distance)
+	}
+	for _, m := range matches {
+		t := 0.0
+		if worst > 0 {
+			t = m.Distance / worst
+		}
+		col := color.RGBA{uint8(255 * t), uint8(255 * (1 - t)), 0, 255}
+		ca, cb := a[m.A].Corner, b[m.B].Corner
+		imaging.DrawLine(out, geometry.Point{X: ca.X, Y: ca.Y}, geometry.Point{X: cb.X + float64(ba.Dx()), Y: cb.Y}, col)
+	}
+	return out
+}
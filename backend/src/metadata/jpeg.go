@@ -0,0 +1,166 @@
+// jpeg comment segments
+
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var jpegSOI = []byte{0xff, 0xd8}
+
+const (
+	markerCOM = 0xfe
+	markerSOS = 0xda
+	// maxSegment is the most data a segment holds, its length field counting itself
+	maxSegment = 0xffff - 2
+)
+
+type jpegSegment struct {
+	marker byte
+	data   []byte
+}
+
+// readSegments splits the header of a jpeg into its marker segments up to the start of scan,
+// returning them with the offset of the SOS marker
+func readSegments(data []byte) ([]jpegSegment, int, error) {
+	var segments []jpegSegment
+	pos := len(jpegSOI)
+	for {
+		if pos+4 > len(data) || data[pos] != 0xff {
+			return nil, 0, fmt.Errorf("%w: bad jpeg marker at %d", ErrCorrupt, pos)
+		}
+		marker := data[pos+1]
+		if marker == 0xff {
+			// fill byte before a marker
+			pos++
+			continue
+		}
+		if marker == markerSOS {
+			return segments, pos, nil
+		}
+		n := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if n < 2 || pos+2+n > len(data) {
+			return nil, 0, fmt.Errorf("%w: jpeg segment overruns the file", ErrCorrupt)
+		}
+		segments = append(segments, jpegSegment{marker: marker, data: data[pos+4 : pos+2+n]})
+		pos += 2 + n
+	}
+}
+
+// commentPrefix starts every comment carrying a part of the payload, numbering the parts so
+// payloads over the segment size can be split
+func commentPrefix(part, parts int) string {
+	return fmt.Sprintf("%s %d/%d\n", Keyword, part, parts)
+}
+
+// parseCommentPrefix returns the part numbering and payload of a comment written by embedJPEG
+func parseCommentPrefix(data []byte) (int, int, []byte, bool) {
+	if !bytes.HasPrefix(data, []byte(Keyword+" ")) {
+		return 0, 0, nil, false
+	}
+	line, rest, ok := bytes.Cut(data[len(Keyword)+1:], []byte("\n"))
+	if !ok {
+		return 0, 0, nil, false
+	}
+	a, b, ok := strings.Cut(string(line), "/")
+	part, errPart := strconv.Atoi(a)
+	parts, errParts := strconv.Atoi(b)
+	if !ok || errPart != nil || errParts != nil || part < 1 || part > parts {
+		return 0, 0, nil, false
+	}
+	return part, parts, rest, true
+}
+
+// embedJPEG writes the payload as COM segments, the jpeg segment meant for text that exiftool and
+// other viewers show as the comment, after the leading APPn segments so JFIF and EXIF stay first.
+// Comments a previous embedding left are dropped.
+func embedJPEG(data, payload []byte) ([]byte, error) {
+	segments, sos, err := readSegments(data)
+	if err != nil {
+		return nil, err
+	}
+	// leave room for the longest prefix the part count allows
+	room := maxSegment - len(commentPrefix(99999, 99999))
+	parts := (len(payload) + room - 1) / room
+	parts = max(parts, 1)
+
+	var buf bytes.Buffer
+	buf.Grow(len(data) + len(payload) + 32*parts)
+	buf.Write(jpegSOI)
+	write := func(marker byte, body []byte) {
+		buf.Write([]byte{0xff, marker})
+		binary.Write(&buf, binary.BigEndian, uint16(len(body)+2))
+		buf.Write(body)
+	}
+	inserted := false
+	insert := func() {
+		for p := 0; p < parts; p++ {
+			chunk := payload[p*room : min((p+1)*room, len(payload))]
+			write(markerCOM, append([]byte(commentPrefix(p+1, parts)), chunk...))
+		}
+		inserted = true
+	}
+	for _, s := range segments {
+		if !inserted && (s.marker < 0xe0 || s.marker > 0xef) {
+			insert()
+		}
+		if s.marker == markerCOM {
+			if _, _, _, ok := parseCommentPrefix(s.data); ok {
+				continue
+			}
+		}
+		write(s.marker, s.data)
+	}
+	if !inserted {
+		insert()
+	}
+	buf.Write(data[sos:])
+	return buf.Bytes(), nil
+}
+
+// extractJPEG joins the payload parts of the COM segments written by embedJPEG. The parts are
+// collected by number rather than into a slice of the announced count, which a corrupt or hostile
+// comment could make arbitrarily large.
+func extractJPEG(data []byte) ([]byte, error) {
+	segments, _, err := readSegments(data)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make(map[int][]byte)
+	parts := 0
+	for _, s := range segments {
+		if s.marker != markerCOM {
+			continue
+		}
+		part, n, rest, ok := parseCommentPrefix(s.data)
+		if !ok {
+			continue
+		}
+		if parts == 0 {
+			parts = n
+		}
+		if n != parts {
+			return nil, fmt.Errorf("%w: inconsistent corner comment numbering", ErrCorrupt)
+		}
+		if _, ok := chunks[part]; ok {
+			return nil, fmt.Errorf("%w: corner comment part %d of %d appears twice", ErrCorrupt, part, parts)
+		}
+		chunks[part] = rest
+	}
+	if parts == 0 {
+		return nil, ErrNoMetadata
+	}
+	var payload []byte
+	for i := 1; i <= parts; i++ {
+		chunk, ok := chunks[i]
+		if !ok {
+			return nil, fmt.Errorf("%w: corner comment part %d of %d is missing", ErrCorrupt, i, parts)
+		}
+		payload = append(payload, chunk...)
+	}
+	return payload, nil
+}
@@ -0,0 +1,95 @@
+// embeds detection results in png and jpeg files and extracts them again
+
+package metadata
+
+import (
+	"Backend/src/detector"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrFormat     = errors.New("metadata can only be embedded in png and jpeg")
+	ErrCorrupt    = errors.New("corrupt image file")
+	ErrNoMetadata = errors.New("no embedded corner metadata")
+)
+
+// Keyword names the png text chunk, and prefixes the jpeg comments, that hold the result
+const Keyword = "corners"
+
+// Embed returns a copy of an encoded png or jpeg carrying result as json, replacing any result
+// embedded before. The pixel data is not touched.
+func Embed(data []byte, result *detector.Result) ([]byte, error) {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		return embedPNG(data, payload)
+	case bytes.HasPrefix(data, jpegSOI):
+		return embedJPEG(data, payload)
+	}
+	return nil, ErrFormat
+}
+
+// Extract returns the result embedded in an encoded png or jpeg
+func Extract(data []byte) (*detector.Result, error) {
+	var payload []byte
+	var err error
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		payload, err = extractPNG(data)
+	case bytes.HasPrefix(data, jpegSOI):
+		payload, err = extractJPEG(data)
+	default:
+		return nil, ErrFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result detector.Result
+	if err := json.Unmarshal(payload, &result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return &result, nil
+}
+
+// Save encodes img as png or jpeg, picked by the extension of filePath, with result embedded
+func Save(img image.Image, filePath string, result *detector.Result) error {
+	var buf bytes.Buffer
+	var err error
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".png":
+		err = png.Encode(&buf, img)
+	case ".jpg", ".jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	default:
+		return fmt.Errorf("%w, not %q", ErrFormat, filepath.Ext(filePath))
+	}
+	if err != nil {
+		return err
+	}
+	data, err := Embed(buf.Bytes(), result)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0o644)
+}
+
+// Load returns the result embedded in the png or jpeg at filePath
+func Load(filePath string) (*detector.Result, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return Extract(data)
+}
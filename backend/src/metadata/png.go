@@ -0,0 +1,160 @@
+// png text chunks
+
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// compressAbove is the payload size from which the iTXt chunk is zlib compressed
+const compressAbove = 1024
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+func readChunks(data []byte) ([]pngChunk, error) {
+	var chunks []pngChunk
+	rest := data[len(pngSignature):]
+	for len(rest) > 0 {
+		if len(rest) < 12 {
+			return nil, fmt.Errorf("%w: truncated png chunk", ErrCorrupt)
+		}
+		n := binary.BigEndian.Uint32(rest)
+		if uint64(n)+12 > uint64(len(rest)) {
+			return nil, fmt.Errorf("%w: png chunk overruns the file", ErrCorrupt)
+		}
+		chunks = append(chunks, pngChunk{kind: string(rest[4:8]), data: rest[8 : 8+n]})
+		rest = rest[12+n:]
+	}
+	if len(chunks) == 0 || chunks[0].kind != "IHDR" {
+		return nil, fmt.Errorf("%w: png does not start with IHDR", ErrCorrupt)
+	}
+	return chunks, nil
+}
+
+func writeChunk(buf *bytes.Buffer, c pngChunk) {
+	var head [8]byte
+	binary.BigEndian.PutUint32(head[:4], uint32(len(c.data)))
+	copy(head[4:], c.kind)
+	buf.Write(head[:])
+	buf.Write(c.data)
+	crc := crc32.NewIEEE()
+	crc.Write(head[4:])
+	crc.Write(c.data)
+	binary.Write(buf, binary.BigEndian, crc.Sum32())
+}
+
+// textKeyword returns the keyword of a tEXt, zTXt or iTXt chunk and the rest of its data
+func textKeyword(c pngChunk) (string, []byte, bool) {
+	if c.kind != "tEXt" && c.kind != "zTXt" && c.kind != "iTXt" {
+		return "", nil, false
+	}
+	i := bytes.IndexByte(c.data, 0)
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(c.data[:i]), c.data[i+1:], true
+}
+
+// embedPNG writes the payload as a utf-8 iTXt chunk right after IHDR, so readers that stop at the
+// first IDAT still see it, and drops text chunks a previous embedding left
+func embedPNG(data, payload []byte) ([]byte, error) {
+	chunks, err := readChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	// keyword, null, compression flag and method, empty language tag and translated keyword
+	text := []byte(Keyword + "\x00\x00\x00\x00\x00")
+	if len(payload) > compressAbove {
+		text[len(Keyword)+1] = 1
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(payload)
+		zw.Close()
+		payload = z.Bytes()
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data) + len(text) + len(payload) + 12)
+	buf.Write(pngSignature)
+	for i, c := range chunks {
+		if keyword, _, ok := textKeyword(c); ok && keyword == Keyword {
+			continue
+		}
+		writeChunk(&buf, c)
+		if i == 0 {
+			writeChunk(&buf, pngChunk{kind: "iTXt", data: append(text, payload...)})
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// extractPNG reads the payload from a tEXt, zTXt or iTXt chunk with the keyword
+func extractPNG(data []byte) ([]byte, error) {
+	chunks, err := readChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chunks {
+		keyword, rest, ok := textKeyword(c)
+		if !ok || keyword != Keyword {
+			continue
+		}
+		switch c.kind {
+		case "tEXt":
+			return rest, nil
+		case "zTXt":
+			if len(rest) < 1 {
+				return nil, fmt.Errorf("%w: short zTXt chunk", ErrCorrupt)
+			}
+			return inflate(rest[1:])
+		}
+		if len(rest) < 2 {
+			return nil, fmt.Errorf("%w: short iTXt chunk", ErrCorrupt)
+		}
+		compressed := rest[0] == 1
+		rest = rest[2:]
+		// skip the language tag and the translated keyword
+		for skip := 0; skip < 2; skip++ {
+			i := bytes.IndexByte(rest, 0)
+			if i < 0 {
+				return nil, fmt.Errorf("%w: unterminated iTXt field", ErrCorrupt)
+			}
+			rest = rest[i+1:]
+		}
+		if compressed {
+			return inflate(rest)
+		}
+		return rest, nil
+	}
+	return nil, ErrNoMetadata
+}
+
+// maxInflated bounds the size a compressed text chunk may inflate to. The result of hundreds of
+// thousands of corners fits, while a few kilobytes of hostile deflate could otherwise take
+// gigabytes.
+const maxInflated = 64 << 20
+
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, maxInflated+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if len(out) > maxInflated {
+		return nil, fmt.Errorf("%w: compressed text inflates past %d bytes", ErrCorrupt, maxInflated)
+	}
+	return out, nil
+}
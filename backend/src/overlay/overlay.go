@@ -0,0 +1,151 @@
+// annotated corner overlays: markers sized and colored by response, orientation ticks and labels
+
+package overlay
+
+import (
+	"Backend/src/corner"
+	"Backend/src/imaging"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrMarker = errors.New("unknown marker")
+	ErrSizeBy = errors.New("unknown marker sizing")
+	ErrColor  = errors.New("invalid color")
+)
+
+// Marker is the shape drawn at each corner
+type Marker string
+
+const (
+	Circle Marker = "circle"
+	Cross  Marker = "cross"
+	Square Marker = "square"
+	Dot    Marker = "dot" // a filled circle
+)
+
+// Markers lists the marker shapes ParseMarker accepts
+var Markers = []Marker{Circle, Cross, Square, Dot}
+
+// SizeBy chooses what the marker radius follows
+type SizeBy string
+
+const (
+	Fixed SizeBy = "fixed" // every marker gets Radius
+	Score SizeBy = "score" // from MinRadius for the weakest corner to Radius for the strongest
+	Scale SizeBy = "scale" // ScaleFactor times the corner's characteristic scale, Radius without one
+)
+
+type Options struct {
+	Marker      Marker
+	Radius      float64 // marker radius in pixels, the largest one when sized by score
+	MinRadius   float64 // radius of the weakest corner when sized by score
+	ScaleFactor float64 // radius per unit of scale when sized by scale
+	SizeBy      SizeBy
+	LineWidth   float64
+	Color       color.RGBA       // marker color when no colormap is set
+	Colormap    imaging.Colormap // when set, colors markers by the rank of their score
+	Ticks       bool             // draw a tick from the center along Corner.Angle, for corners that have one
+	Labels      bool             // write the index of each corner beside its marker
+	Antialias   bool
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Marker:      Circle,
+		Radius:      5,
+		MinRadius:   2,
+		ScaleFactor: 2,
+		SizeBy:      Fixed,
+		LineWidth:   1.5,
+		Color:       color.RGBA{255, 0, 0, 255},
+		Antialias:   true,
+	}
+}
+
+func ParseMarker(value string) (Marker, error) {
+	for _, m := range Markers {
+		if Marker(value) == m {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrMarker, value)
+}
+
+func ParseSizeBy(value string) (SizeBy, error) {
+	switch SizeBy(value) {
+	case Fixed, Score, Scale:
+		return SizeBy(value), nil
+	}
+	return "", fmt.Errorf("%w %q", ErrSizeBy, value)
+}
+
+// ParseColor reads a color as #rrggbb or #rrggbbaa, the leading # being optional
+func ParseColor(value string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.RGBA{}, fmt.Errorf("%w %q", ErrColor, value)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("%w %q", ErrColor, value)
+	}
+	return color.RGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+// mark is a corner resolved to what gets drawn for it
+type mark struct {
+	Index  int
+	X, Y   float64
+	Radius float64
+	Color  color.RGBA
+	Corner corner.Corner
+}
+
+// marks resolves the radius and color of every corner. Score driven sizes and colors use the rank
+// of the score rather than its value, since responses often span several decades.
+func (o Options) marks(corners []corner.Corner) []mark {
+	rank := make([]float64, len(corners))
+	order := make([]int, len(corners))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return corners[order[a]].Score < corners[order[b]].Score
+	})
+	for r, i := range order {
+		if len(corners) > 1 {
+			rank[i] = float64(r) / float64(len(corners)-1)
+		} else {
+			rank[i] = 1
+		}
+	}
+
+	marks := make([]mark, len(corners))
+	for i, c := range corners {
+		radius := o.Radius
+		switch o.SizeBy {
+		case Score:
+			radius = o.MinRadius + rank[i]*(o.Radius-o.MinRadius)
+		case Scale:
+			if c.Scale > 0 {
+				radius = o.ScaleFactor * c.Scale
+			}
+		}
+		col := o.Color
+		if o.Colormap != "" {
+			col = o.Colormap.At(rank[i])
+		}
+		marks[i] = mark{Index: i, X: c.X, Y: c.Y, Radius: math.Max(radius, 0.5), Color: col, Corner: c}
+	}
+	return marks
+}
@@ -0,0 +1,135 @@
+// raster rendering of overlays, every shape drawn through its signed distance so edges can be
+// anti-aliased by pixel coverage
+
+package overlay
+
+import (
+	"Backend/src/corner"
+	"Backend/src/geometry"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+)
+
+// Render copies img and draws every corner on it as configured
+func Render(img image.Image, corners []corner.Corner, opts Options) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(bounds)
+	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+	c := canvas{img: rgba, origin: bounds.Min, antialias: opts.Antialias}
+	half := opts.LineWidth / 2
+
+	for _, m := range opts.marks(corners) {
+		center := geometry.Point{X: m.X, Y: m.Y}
+		r := m.Radius
+		switch opts.Marker {
+		case Dot:
+			c.fill(center, r+1, m.Color, func(p geometry.Point) float64 {
+				return p.Dist(center) - r
+			})
+		case Cross:
+			c.fill(center, r+half+1, m.Color, func(p geometry.Point) float64 {
+				h := segmentDist(p, center.Add(geometry.Point{X: -r}), center.Add(geometry.Point{X: r}))
+				v := segmentDist(p, center.Add(geometry.Point{Y: -r}), center.Add(geometry.Point{Y: r}))
+				return math.Min(h, v) - half
+			})
+		case Square:
+			c.fill(center, r+half+1, m.Color, func(p geometry.Point) float64 {
+				d := math.Max(math.Abs(p.X-center.X), math.Abs(p.Y-center.Y)) - r
+				return math.Abs(d) - half
+			})
+		default:
+			c.fill(center, r+half+1, m.Color, func(p geometry.Point) float64 {
+				return math.Abs(p.Dist(center)-r) - half
+			})
+		}
+		if opts.Ticks && m.Corner.HasAngle {
+			a := m.Corner.Angle * math.Pi / 180
+			end := center.Add(geometry.Point{X: math.Cos(a), Y: math.Sin(a)}.Scale(r + 3))
+			c.fill(center, r+half+4, m.Color, func(p geometry.Point) float64 {
+				return segmentDist(p, center, end) - half
+			})
+		}
+		if opts.Labels {
+			c.text(strconv.Itoa(m.Index), int(math.Round(m.X+r+2)), int(math.Round(m.Y-r-2-2*glyphHeight)), 2, m.Color)
+		}
+	}
+	return rgba
+}
+
+// canvas draws onto an RGBA image in coordinates relative to its origin
+type canvas struct {
+	img       *image.RGBA
+	origin    image.Point
+	antialias bool
+}
+
+// fill paints every pixel within reach of center whose center lies inside the shape sdf describes,
+// blending by coverage when anti-aliasing
+func (c canvas) fill(center geometry.Point, reach float64, col color.RGBA, sdf func(geometry.Point) float64) {
+	x0, x1 := int(math.Floor(center.X-reach)), int(math.Ceil(center.X+reach))
+	y0, y1 := int(math.Floor(center.Y-reach)), int(math.Ceil(center.Y+reach))
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			d := sdf(geometry.Point{X: float64(x), Y: float64(y)})
+			coverage := 0.0
+			if c.antialias {
+				coverage = math.Max(0, math.Min(1, 0.5-d))
+			} else if d <= 0 {
+				coverage = 1
+			}
+			if coverage > 0 {
+				c.blend(x, y, col, coverage)
+			}
+		}
+	}
+}
+
+// blend mixes col into the pixel at (x, y) with the given coverage times the color's own alpha
+func (c canvas) blend(x, y int, col color.RGBA, coverage float64) {
+	p := image.Pt(c.origin.X+x, c.origin.Y+y)
+	if !p.In(c.img.Rect) {
+		return
+	}
+	a := coverage * float64(col.A) / 255
+	dst := c.img.RGBAAt(p.X, p.Y)
+	mix := func(d, s uint8) uint8 {
+		return uint8(math.Round(float64(d)*(1-a) + float64(s)*a))
+	}
+	c.img.SetRGBA(p.X, p.Y, color.RGBA{mix(dst.R, col.R), mix(dst.G, col.G), mix(dst.B, col.B), 255})
+}
+
+// text writes decimal digits with the built in bitmap font, each font pixel scaled to a square
+func (c canvas) text(s string, x, y, scale int, col color.RGBA) {
+	for i, ch := range s {
+		glyph, ok := digits[ch]
+		if !ok {
+			continue
+		}
+		left := x + i*(glyphWidth+1)*scale
+		for row, bits := range glyph {
+			for column := 0; column < glyphWidth; column++ {
+				if bits&(1<<(glyphWidth-1-column)) == 0 {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						c.blend(left+column*scale+dx, y+row*scale+dy, col, 1)
+					}
+				}
+			}
+		}
+	}
+}
+
+// segmentDist returns the distance from p to the segment ab
+func segmentDist(p, a, b geometry.Point) float64 {
+	ab := b.Sub(a)
+	t := 0.0
+	if l := ab.Dot(ab); l > 0 {
+		t = math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/l))
+	}
+	return p.Dist(a.Add(ab.Scale(t)))
+}
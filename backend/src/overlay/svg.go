@@ -0,0 +1,93 @@
+// vector overlays: the same markers as Render written as an svg layer for zooming and css styling
+
+package overlay
+
+import (
+	"Backend/src/corner"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+)
+
+// SVGOptions says what goes around the markers of an svg overlay
+type SVGOptions struct {
+	Detector  string      // recorded as data-detector on every corner
+	Image     image.Image // embedded as a base64 png background when set
+	ImageHref string      // referenced as the background when Image is nil, none when empty too
+}
+
+// WriteSVG writes corners as an svg document of the given size. Every corner is a group of class
+// "corner" carrying data-index, data-score, data-detector and, when known, data-scale and
+// data-angle, holding its marker and optionally a tick of class "tick" and a label of class
+// "label". Colors and widths are presentation attributes, so a stylesheet can override them.
+func WriteSVG(w io.Writer, corners []corner.Corner, width, height int, opts Options, svg SVGOptions) error {
+	bw := bufio.NewWriter(w)
+	num := func(v float64) string {
+		return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+	}
+	// paint returns a fill or stroke attribute, with its opacity when the color is translucent
+	paint := func(attr string, c color.RGBA) string {
+		s := fmt.Sprintf(`%s="#%02x%02x%02x"`, attr, c.R, c.G, c.B)
+		if c.A != 255 {
+			s += fmt.Sprintf(` %s-opacity="%s"`, attr, num(float64(c.A)/255))
+		}
+		return s
+	}
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	href := html.EscapeString(svg.ImageHref)
+	if svg.Image != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, svg.Image); err != nil {
+			return err
+		}
+		href = "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	if href != "" {
+		fmt.Fprintf(bw, `<image class="background" x="0" y="0" width="%d" height="%d" href="%s" xlink:href="%s"/>`+"\n", width, height, href, href)
+	}
+
+	fmt.Fprintf(bw, `<g class="corners" stroke-width="%s">`+"\n", num(opts.LineWidth))
+	for _, m := range opts.marks(corners) {
+		c := m.Corner
+		fmt.Fprintf(bw, `<g class="corner marker-%s" data-index="%d" data-score="%g" data-detector="%s"`, opts.Marker, m.Index, c.Score, html.EscapeString(svg.Detector))
+		if c.Scale > 0 {
+			fmt.Fprintf(bw, ` data-scale="%g"`, c.Scale)
+		}
+		if c.HasAngle {
+			fmt.Fprintf(bw, ` data-angle="%g"`, c.Angle)
+		}
+		bw.WriteString(">")
+
+		x, y, r := num(m.X), num(m.Y), m.Radius
+		fill, stroke := paint("fill", m.Color), paint("stroke", m.Color)
+		switch opts.Marker {
+		case Dot:
+			fmt.Fprintf(bw, `<circle cx="%s" cy="%s" r="%s" %s stroke="none"/>`, x, y, num(r), fill)
+		case Cross:
+			fmt.Fprintf(bw, `<path d="M%s %sh%sM%s %sv%s" fill="none" %s/>`, num(m.X-r), y, num(2*r), x, num(m.Y-r), num(2*r), stroke)
+		case Square:
+			fmt.Fprintf(bw, `<rect x="%s" y="%s" width="%s" height="%s" fill="none" %s/>`, num(m.X-r), num(m.Y-r), num(2*r), num(2*r), stroke)
+		default:
+			fmt.Fprintf(bw, `<circle cx="%s" cy="%s" r="%s" fill="none" %s/>`, x, y, num(r), stroke)
+		}
+		if opts.Ticks && c.HasAngle {
+			a := c.Angle * math.Pi / 180
+			fmt.Fprintf(bw, `<line class="tick" x1="%s" y1="%s" x2="%s" y2="%s" %s/>`, x, y, num(m.X+math.Cos(a)*(r+3)), num(m.Y+math.Sin(a)*(r+3)), stroke)
+		}
+		if opts.Labels {
+			fmt.Fprintf(bw, `<text class="label" x="%s" y="%s" font-size="10" font-family="sans-serif" %s>%d</text>`, num(m.X+r+2), num(m.Y-r-2), fill, m.Index)
+		}
+		bw.WriteString("</g>\n")
+	}
+	bw.WriteString("</g>\n</svg>\n")
+	return bw.Flush()
+}
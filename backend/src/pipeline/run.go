@@ -0,0 +1,199 @@
+// running a spec on images and writing its outputs
+
+package pipeline
+
+import (
+	"Backend/src/chessboard"
+	"Backend/src/corner"
+	"Backend/src/detector"
+	"Backend/src/distribution"
+	"Backend/src/geometry"
+	"Backend/src/imaging"
+	"Backend/src/overlay"
+	"Backend/src/serialize"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Detection is the result of one detector of a spec on one image
+type Detection struct {
+	Label  string           `json:"label"`
+	Result *detector.Result `json:"result"`
+}
+
+// Run detects corners with every detector of the spec and post-processes them. Every result
+// carries the config hash of the spec.
+func (s *Spec) Run(img image.Image) ([]Detection, error) {
+	hash := s.Hash()
+	var gray *imaging.Float
+	detections := make([]Detection, len(s.Detectors))
+	for i, d := range s.Detectors {
+		result, err := detector.Detect(img, s.config(d))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.label(), err)
+		}
+		for _, p := range s.Postprocess {
+			if p.Op == OpSubpixel && gray == nil {
+				gray = imaging.ToFloat(img)
+			}
+			if result.Corners, err = apply(p, result.Corners, gray, result.Width, result.Height); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", d.label(), p.Op, err)
+			}
+		}
+		result.ConfigHash = hash
+		detections[i] = Detection{Label: d.label(), Result: result}
+	}
+	return detections, nil
+}
+
+// apply runs one post-processing operation on the corners of a width x height image. gray is only
+// read by subpixel.
+func apply(p Post, corners []corner.Corner, gray *imaging.Float, width, height int) ([]corner.Corner, error) {
+	switch p.Op {
+	case OpNMS:
+		return suppress(corners, p.Radius), nil
+	case OpSubpixel:
+		return refine(corners, gray, int(math.Ceil(p.Radius))), nil
+	case OpTop:
+		sorted := append([]corner.Corner(nil), corners...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Score > sorted[j].Score
+		})
+		if len(sorted) > p.Count {
+			sorted = sorted[:p.Count]
+		}
+		return sorted, nil
+	}
+	return distribution.Apply(p.Op, corners, width, height, p.Count)
+}
+
+// suppress keeps the strongest corners and drops every corner closer than radius to one already
+// kept, using a grid of radius sized cells so only neighbouring cells are searched
+func suppress(corners []corner.Corner, radius float64) []corner.Corner {
+	sorted := append([]corner.Corner(nil), corners...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Score > sorted[j].Score
+	})
+	type cell struct{ x, y int }
+	grid := map[cell][]corner.Corner{}
+	kept := sorted[:0]
+	for _, c := range sorted {
+		at := cell{int(math.Floor(c.X / radius)), int(math.Floor(c.Y / radius))}
+		near := false
+		for dy := -1; dy <= 1 && !near; dy++ {
+			for dx := -1; dx <= 1 && !near; dx++ {
+				for _, k := range grid[cell{at.x + dx, at.y + dy}] {
+					if math.Hypot(k.X-c.X, k.Y-c.Y) < radius {
+						near = true
+						break
+					}
+				}
+			}
+		}
+		if !near {
+			grid[at] = append(grid[at], c)
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// refine moves every corner to sub-pixel accuracy with the gradient orthogonality condition
+func refine(corners []corner.Corner, gray *imaging.Float, radius int) []corner.Corner {
+	dx, dy := imaging.Derivatives(imaging.Gaussian(gray, 1))
+	refined := make([]corner.Corner, len(corners))
+	for i, c := range corners {
+		p := chessboard.RefineCorner(dx, dy, geometry.Point{X: c.X, Y: c.Y}, radius)
+		c.X, c.Y = p.X, p.Y
+		refined[i] = c
+	}
+	return refined
+}
+
+// Expand fills the placeholders of an output path for an image and a detector label
+func (s *Spec) Expand(path, name, label string) string {
+	return strings.NewReplacer(
+		PlaceholderName, name,
+		PlaceholderDetector, label,
+		PlaceholderHash, s.Hash()[:12],
+	).Replace(path)
+}
+
+// Execute runs the spec on img and writes every output below dir, naming them after the input
+// file. It returns the detections and the paths written.
+func (s *Spec) Execute(img image.Image, input, dir string) ([]Detection, []string, error) {
+	detections, err := s.Run(img)
+	if err != nil {
+		return nil, nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+	var written []string
+	for _, d := range detections {
+		for _, o := range s.Outputs {
+			expanded := filepath.FromSlash(s.Expand(o.path(), name, d.Label))
+			if escapes(dir, expanded) {
+				return detections, written, fmt.Errorf("%w: %s", ErrOutputPath, expanded)
+			}
+			path := filepath.Join(dir, expanded)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return detections, written, err
+			}
+			if err := write(o, path, img, input, d); err != nil {
+				return detections, written, fmt.Errorf("%s: %w", path, err)
+			}
+			written = append(written, path)
+		}
+	}
+	return detections, written, nil
+}
+
+// escapes reports whether the output path p is absolute or, joined to dir, does not name a file
+// below dir
+func escapes(dir, p string) bool {
+	if filepath.IsAbs(p) || filepath.VolumeName(p) != "" || strings.HasPrefix(p, string(filepath.Separator)) {
+		return true
+	}
+	rel, err := filepath.Rel(dir, filepath.Join(dir, p))
+	return err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func write(o Output, path string, img image.Image, input string, d Detection) error {
+	switch o.Kind {
+	case KindOverlay:
+		return imaging.Save(overlay.Render(img, d.Result.Corners, overlay.DefaultOptions()), path)
+	case KindSVG:
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		// the image is referenced relative to the svg, so both can move together
+		href, err := filepath.Rel(filepath.Dir(path), input)
+		if err != nil {
+			href = input
+		}
+		svg := overlay.SVGOptions{Detector: d.Result.Detector, ImageHref: filepath.ToSlash(href)}
+		if err := overlay.WriteSVG(f, d.Result.Corners, d.Result.Width, d.Result.Height, overlay.DefaultOptions(), svg); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	format := o.Format
+	if format == "" {
+		format = serialize.JSON
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := serialize.Write(f, d.Result, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,365 @@
+// declarative pipeline specs: decode, preprocess, detectors, post-processing and outputs read from
+// yaml or json, validated up front and hashed so every result names the settings behind it
+
+package pipeline
+
+import (
+	"Backend/src/detector"
+	"Backend/src/imaging"
+	"Backend/src/preprocess"
+	"Backend/src/serialize"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrSyntax     = errors.New("pipeline spec syntax")
+	ErrInvalid    = errors.New("invalid pipeline spec")
+	ErrOutputPath = errors.New("output path leaves the output directory")
+)
+
+// Version is the spec version this package reads
+const Version = 1
+
+// Post-processing operations, applied in the order listed
+const (
+	OpNMS      = "nms"      // drop corners within radius of a stronger one
+	OpSubpixel = "subpixel" // move corners to where the gradients in a radius window are orthogonal
+	OpTop      = "top"      // keep the count strongest corners
+	OpANMS     = "anms"     // adaptive non-maximal suppression down to count corners
+	OpSSC      = "ssc"      // suppression via square covering down to count corners
+	OpGrid     = "grid"     // count corners spread over a grid of cells
+)
+
+// Ops lists the post-processing operations a spec accepts
+var Ops = []string{OpNMS, OpSubpixel, OpTop, OpANMS, OpSSC, OpGrid}
+
+// Output kinds
+const (
+	KindResult  = "result"  // the corners in a serialize format
+	KindOverlay = "overlay" // the corners drawn over the image as a png
+	KindSVG     = "svg"     // the corners as an svg referencing the image
+)
+
+// Path placeholders expanded for every image and detector
+const (
+	PlaceholderName     = "{name}"     // input base name without extension
+	PlaceholderDetector = "{detector}" // detector label
+	PlaceholderHash     = "{hash}"     // first 12 characters of the config hash
+)
+
+// Spec is a whole pipeline. Preprocess and color apply to every detector that does not set its
+// own; a preprocess left out means the default of each detector.
+type Spec struct {
+	Version     int                 `json:"version" yaml:"version"`
+	Name        string              `json:"name,omitempty" yaml:"name,omitempty"`
+	Decode      Decode              `json:"decode" yaml:"decode"`
+	Preprocess  preprocess.Pipeline `json:"preprocess,omitempty" yaml:"preprocess,omitempty"`
+	Detectors   []Detector          `json:"detectors" yaml:"detectors"`
+	Postprocess []Post              `json:"postprocess,omitempty" yaml:"postprocess,omitempty"`
+	Outputs     []Output            `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+}
+
+// Decode shapes how the input image is turned into detector channels
+type Decode struct {
+	Color imaging.ColorSpace `json:"color,omitempty" yaml:"color,omitempty"`
+}
+
+// Detector is one detector run on every image. Label names it in output paths and defaults to the
+// detector, so a detector used twice needs distinct labels.
+type Detector struct {
+	Label      string              `json:"label,omitempty" yaml:"label,omitempty"`
+	Detector   string              `json:"detector" yaml:"detector"`
+	Params     detector.Params     `json:"params,omitempty" yaml:"params,omitempty"`
+	Preprocess preprocess.Pipeline `json:"preprocess,omitempty" yaml:"preprocess,omitempty"`
+	Color      imaging.ColorSpace  `json:"color,omitempty" yaml:"color,omitempty"`
+}
+
+// Post is one post-processing operation. Radius is used by nms and subpixel, count by the others.
+type Post struct {
+	Op     string  `json:"op" yaml:"op"`
+	Radius float64 `json:"radius,omitempty" yaml:"radius,omitempty"`
+	Count  int     `json:"count,omitempty" yaml:"count,omitempty"`
+}
+
+// Output is a file written for every image and detector, at Path below the output directory after
+// expanding the placeholders
+type Output struct {
+	Kind   string           `json:"kind" yaml:"kind"`
+	Format serialize.Format `json:"format,omitempty" yaml:"format,omitempty"` // for results, default json
+	Path   string           `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// Example is a spec using every stage, as printed by "corners pipeline -example"
+const Example = `# corner detection pipeline, version 1
+version: 1
+name: example
+decode:
+  color: gray            # gray, rgb or lab (rgb and lab for harris and shi-tomashi only)
+preprocess: median:3     # applied before every detector that sets none, see preprocess.Parse
+detectors:
+  - detector: harris
+    params: { k: 0.04, min_dist: 5 }
+  - detector: fast
+    params: { threshold: 30 }
+    preprocess: none     # overrides the shared preprocessing
+postprocess:             # applied in order to the corners of every detector
+  - op: nms
+    radius: 3
+  - op: subpixel
+    radius: 4
+  - op: anms
+    count: 500
+outputs:                 # {name}, {detector} and {hash} are expanded in paths
+  - kind: result
+    format: json
+    path: "{name}.{detector}.json"
+  - kind: overlay
+    path: "{name}.{detector}.png"
+`
+
+// Parse reads a spec from json, when it starts with "{", or yaml. Unknown fields are errors so a
+// misspelt option cannot be silently ignored. The spec is validated and its defaults filled in.
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&spec); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&spec); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	spec.fillDefaults()
+	return &spec, nil
+}
+
+// Load reads and validates the spec file at path
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// Validate checks the whole spec and reports every problem found at once, each with the path of
+// the field at fault
+func (s *Spec) Validate() error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	if s.Version != Version {
+		add("version: must be %d, not %d", Version, s.Version)
+	}
+	decodeColor := s.Decode.Color
+	if _, err := imaging.ParseColorSpace(string(decodeColor)); err != nil {
+		add("decode.color: %v", err)
+		// reported once here rather than again for every detector
+		decodeColor = ""
+	}
+
+	if len(s.Detectors) == 0 {
+		add("detectors: at least one is required")
+	}
+	labels := map[string]bool{}
+	for i, d := range s.Detectors {
+		field := fmt.Sprintf("detectors[%d]", i)
+		if _, err := imaging.ParseColorSpace(string(d.Color)); d.Color != "" && err != nil {
+			add("%s.color: %v", field, err)
+		} else {
+			cfg := s.config(d)
+			if d.Color == "" {
+				cfg.Color = decodeColor
+			}
+			if err := detector.Validate(cfg); err != nil {
+				add("%s: %v", field, err)
+			}
+		}
+		label := d.label()
+		if strings.ContainsAny(label, `/\`) {
+			add("%s.label: %q cannot contain path separators", field, label)
+		}
+		if label == "." || label == ".." {
+			add("%s.label: %q would name a directory, not a file", field, label)
+		}
+		if labels[label] {
+			add("%s: label %q is used twice, give one of them another label", field, label)
+		}
+		labels[label] = true
+	}
+
+	for i, p := range s.Postprocess {
+		field := fmt.Sprintf("postprocess[%d]", i)
+		switch p.Op {
+		case OpNMS, OpSubpixel:
+			if p.Radius <= 0 {
+				add("%s: %s needs a positive radius", field, p.Op)
+			}
+			if p.Count != 0 {
+				add("%s: %s takes no count", field, p.Op)
+			}
+		case OpTop, OpANMS, OpSSC, OpGrid:
+			if p.Count <= 0 {
+				add("%s: %s needs a positive count", field, p.Op)
+			}
+			if p.Radius != 0 {
+				add("%s: %s takes no radius", field, p.Op)
+			}
+		default:
+			add("%s.op: unknown operation %q, expected one of %s", field, p.Op, strings.Join(Ops, ", "))
+		}
+	}
+
+	paths := map[string]bool{}
+	for i, o := range s.Outputs {
+		field := fmt.Sprintf("outputs[%d]", i)
+		switch o.Kind {
+		case KindResult:
+			if o.Format != "" {
+				if _, err := serialize.ParseFormat(string(o.Format)); err != nil {
+					add("%s.format: %v", field, err)
+				}
+			}
+		case KindOverlay, KindSVG:
+			if o.Format != "" {
+				add("%s: %s outputs take no format", field, o.Kind)
+			}
+		default:
+			add("%s.kind: unknown kind %q, expected result, overlay or svg", field, o.Kind)
+			continue
+		}
+		path := o.path()
+		if len(s.Detectors) > 1 && !strings.Contains(path, PlaceholderDetector) {
+			add("%s.path: %q needs %s to keep the detectors apart", field, path, PlaceholderDetector)
+		}
+		if !strings.Contains(path, PlaceholderName) {
+			add("%s.path: %q needs %s to keep the images apart", field, path, PlaceholderName)
+		}
+		// placeholders are checked by Execute once expanded, stand-ins are enough here
+		probe := strings.NewReplacer(PlaceholderName, "x", PlaceholderDetector, "x", PlaceholderHash, "x").Replace(path)
+		if escapes(".", filepath.FromSlash(probe)) {
+			add("%s.path: %q must be relative and stay inside the output directory", field, path)
+		}
+		if paths[path] {
+			add("%s.path: %q is written twice", field, path)
+		}
+		paths[path] = true
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w:\n  %s", ErrInvalid, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// fillDefaults resolves what a validated spec left out: json results, named after the image and
+// detector, when no outputs are listed
+func (s *Spec) fillDefaults() {
+	if len(s.Outputs) == 0 {
+		s.Outputs = []Output{{Kind: KindResult}}
+	}
+	for i, o := range s.Outputs {
+		if o.Kind == KindResult && o.Format == "" {
+			s.Outputs[i].Format = serialize.JSON
+		} else if o.Kind == KindResult {
+			s.Outputs[i].Format, _ = serialize.ParseFormat(string(o.Format))
+		}
+		s.Outputs[i].Path = s.Outputs[i].path()
+	}
+}
+
+func (d Detector) label() string {
+	if d.Label != "" {
+		return d.Label
+	}
+	return d.Detector
+}
+
+func (o Output) path() string {
+	if o.Path != "" {
+		return o.Path
+	}
+	switch o.Kind {
+	case KindOverlay:
+		return PlaceholderName + "." + PlaceholderDetector + ".png"
+	case KindSVG:
+		return PlaceholderName + "." + PlaceholderDetector + ".svg"
+	}
+	format := o.Format
+	if format == "" {
+		format = serialize.JSON
+	}
+	return PlaceholderName + "." + PlaceholderDetector + format.Ext()
+}
+
+// config returns the detector config of d, with the shared preprocessing and color where d sets
+// none
+func (s *Spec) config(d Detector) detector.Config {
+	cfg := detector.Config{Detector: d.Detector, Params: d.Params, Preprocess: d.Preprocess, Color: d.Color}
+	if cfg.Preprocess == nil {
+		cfg.Preprocess = s.Preprocess
+	}
+	if cfg.Color == "" {
+		cfg.Color = s.Decode.Color
+	}
+	return cfg
+}
+
+// Hash identifies the settings that shape the detected corners: the version, the detector configs
+// with their defaults resolved, and the post-processing. Labels, the name and the outputs do not
+// change the corners and are left out, so renaming a spec or adding an output keeps its hash.
+func (s *Spec) Hash() string {
+	configs := s.Configs()
+	for i, cfg := range configs {
+		params := detector.DefaultParams(cfg.Detector)
+		for name, value := range cfg.Params {
+			params[name] = value
+		}
+		configs[i].Params = params
+		if cfg.Preprocess == nil {
+			configs[i].Preprocess = detector.DefaultPreprocess(cfg.Detector)
+		}
+		if cfg.Color == "" {
+			configs[i].Color = imaging.Gray
+		}
+	}
+	data, _ := json.Marshal(struct {
+		Version     int               `json:"version"`
+		Detectors   []detector.Config `json:"detectors"`
+		Postprocess []Post            `json:"postprocess"`
+	}{s.Version, configs, s.Postprocess})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Configs returns the resolved detector config of every detector, in order
+func (s *Spec) Configs() []detector.Config {
+	configs := make([]detector.Config, len(s.Detectors))
+	for i, d := range s.Detectors {
+		configs[i] = s.config(d)
+	}
+	return configs
+}
@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"Backend/src/detector"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// spec returns a one detector yaml spec with the given label and result path
+func spec(label, path string) string {
+	return fmt.Sprintf(`version: 1
+detectors:
+  - detector: harris
+    label: %q
+outputs:
+  - kind: result
+    path: %q
+`, label, path)
+}
+
+func TestParseRejectsEscapingLabelsAndPaths(t *testing.T) {
+	tests := []struct {
+		name, label, path string
+		want              string // field the problem is reported on, empty when the spec is valid
+	}{
+		{"plain", "corners", "{name}.{detector}.json", ""},
+		{"subdirectory", "corners", "{detector}/{name}.json", ""},
+		{"dot dot that stays inside", "corners", "a/../{name}.{detector}.json", ""},
+		{"dot label", ".", "{name}/{detector}", ".label"},
+		{"dot dot label", "..", "{name}/{detector}", ".label"},
+		{"absolute path", "corners", "/tmp/{name}.{detector}.json", ".path"},
+		{"parent directory", "corners", "../{name}.{detector}.json", ".path"},
+		{"parent directory after a placeholder", "corners", "{name}/../../{detector}.json", ".path"},
+		{"output directory itself", "corners", "{name}/..", ".path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(spec(tt.label, tt.path)))
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("got %v, want %v", err, ErrInvalid)
+			}
+			if !strings.Contains(err.Error(), tt.want+":") {
+				t.Errorf("%q does not report %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteRejectsEscapingNames(t *testing.T) {
+	s, err := Parse([]byte(spec("corners", "{name}/{detector}.json")))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	img := image.NewGray(image.Rect(0, 0, 32, 32))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+	root := t.TempDir()
+	dir := filepath.Join(root, "out")
+
+	// "..." loses its extension "." and leaves a {name} of ".."
+	_, written, err := s.Execute(img, filepath.Join(root, "..."), dir)
+	if !errors.Is(err, ErrOutputPath) {
+		t.Fatalf("got %v, want %v", err, ErrOutputPath)
+	}
+	if len(written) > 0 {
+		t.Errorf("wrote %v", written)
+	}
+	if _, err := os.Stat(filepath.Join(root, "corners.json")); !os.IsNotExist(err) {
+		t.Errorf("a result was written outside the output directory")
+	}
+
+	_, written, err = s.Execute(img, filepath.Join(root, "board.png"), dir)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if want := filepath.Join(dir, "board", "corners.json"); len(written) != 1 || written[0] != want {
+		t.Errorf("wrote %v, want %s", written, want)
+	}
+}
+
+func TestHashResolvesDefaultParams(t *testing.T) {
+	defaults := detector.DefaultParams("harris")
+	parse := func(params string) *Spec {
+		s, err := Parse([]byte("version: 1\ndetectors:\n  - detector: harris\n    params: {" + params + "}\n"))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		return s
+	}
+	base := parse("").Hash()
+	tests := []struct {
+		name   string
+		params string
+		same   bool
+	}{
+		{"explicit default threshold", fmt.Sprintf("threshold: %v", defaults["threshold"]), true},
+		{"every param at its default", fmt.Sprintf("threshold: %v, k: %v, window: %v, min_dist: %v",
+			defaults["threshold"], defaults["k"], defaults["window"], defaults["min_dist"]), true},
+		{"other threshold", fmt.Sprintf("threshold: %v", 2*defaults["threshold"]), false},
+		{"other window", fmt.Sprintf("window: %v", defaults["window"]+2), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parse(tt.params).Hash() == base; got != tt.same {
+				t.Errorf("hash equal to the defaults' = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// the individual preprocessing filters, each returning a new image on the 0..255 gray scale
+
+package preprocess
+
+import (
+	"Backend/src/imaging"
+	"math"
+	"sort"
+)
+
+// Median replaces every pixel by the median of its k×k neighbourhood, replicating the border. It
+// reads only from the source so already filtered pixels never feed back into their neighbours.
+func Median(f *imaging.Float, k int) *imaging.Float {
+	out := imaging.NewFloat(f.W, f.H)
+	r := k / 2
+	window := make([]float64, 0, k*k)
+	for y := 0; y < f.H; y++ {
+		for x := 0; x < f.W; x++ {
+			window = window[:0]
+			for j := -r; j <= r; j++ {
+				for i := -r; i <= r; i++ {
+					window = append(window, f.Clamped(x+i, y+j))
+				}
+			}
+			sort.Float64s(window)
+			out.Set(x, y, window[len(window)/2])
+		}
+	}
+	return out
+}
+
+// Box replaces every pixel by the mean of its k×k neighbourhood
+func Box(f *imaging.Float, k int) *imaging.Float {
+	kernel := make([]float64, k)
+	for i := range kernel {
+		kernel[i] = 1 / float64(k)
+	}
+	return imaging.Separable(f, kernel, kernel)
+}
+
+// Bilateral averages each pixel with its neighbours weighted by both distance and intensity
+// difference, smoothing noise while keeping edges and so the corners on them
+func Bilateral(f *imaging.Float, sigmaSpace, sigmaRange float64) *imaging.Float {
+	r := int(math.Ceil(2 * sigmaSpace))
+	spatial := make([]float64, (2*r+1)*(2*r+1))
+	for j := -r; j <= r; j++ {
+		for i := -r; i <= r; i++ {
+			spatial[(j+r)*(2*r+1)+i+r] = math.Exp(-float64(i*i+j*j) / (2 * sigmaSpace * sigmaSpace))
+		}
+	}
+	// intensity weights are tabulated per whole gray level difference
+	rangeWeight := make([]float64, 256)
+	for d := range rangeWeight {
+		rangeWeight[d] = math.Exp(-float64(d*d) / (2 * sigmaRange * sigmaRange))
+	}
+	out := imaging.NewFloat(f.W, f.H)
+	for y := 0; y < f.H; y++ {
+		for x := 0; x < f.W; x++ {
+			center := f.At(x, y)
+			sum, weights := 0.0, 0.0
+			for j := -r; j <= r; j++ {
+				for i := -r; i <= r; i++ {
+					v := f.Clamped(x+i, y+j)
+					d := min(int(math.Abs(v-center)+0.5), 255)
+					w := spatial[(j+r)*(2*r+1)+i+r] * rangeWeight[d]
+					sum += w * v
+					weights += w
+				}
+			}
+			out.Set(x, y, sum/weights)
+		}
+	}
+	return out
+}
+
+// histogram counts the pixels of f per gray level, clamping to 0..255
+func histogram(f *imaging.Float, x0, y0, x1, y1 int) [256]float64 {
+	var h [256]float64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			h[level(f.At(x, y))]++
+		}
+	}
+	return h
+}
+
+func level(v float64) int {
+	return min(max(int(v+0.5), 0), 255)
+}
+
+// mapping turns a histogram into the cumulative lookup table of histogram equalization
+func mapping(h [256]float64) [256]float64 {
+	var lut [256]float64
+	total := 0.0
+	for _, c := range h {
+		total += c
+	}
+	if total == 0 {
+		return lut
+	}
+	cum := 0.0
+	for i, c := range h {
+		cum += c
+		lut[i] = 255 * cum / total
+	}
+	return lut
+}
+
+// Equalize spreads the gray levels so their cumulative histogram becomes linear
+func Equalize(f *imaging.Float) *imaging.Float {
+	lut := mapping(histogram(f, 0, 0, f.W, f.H))
+	out := imaging.NewFloat(f.W, f.H)
+	for i, v := range f.Pix {
+		out.Pix[i] = lut[level(v)]
+	}
+	return out
+}
+
+// CLAHE equalizes each of tiles×tiles regions separately, clipping every histogram bin at clip
+// times the mean bin count and spreading the excess evenly, then blends the four nearest tile
+// mappings bilinearly so no seams appear between tiles
+func CLAHE(f *imaging.Float, clip float64, tiles int) *imaging.Float {
+	tiles = max(1, min(tiles, f.W, f.H))
+	luts := make([][256]float64, tiles*tiles)
+	for ty := 0; ty < tiles; ty++ {
+		for tx := 0; tx < tiles; tx++ {
+			x0, x1 := tx*f.W/tiles, (tx+1)*f.W/tiles
+			y0, y1 := ty*f.H/tiles, (ty+1)*f.H/tiles
+			h := histogram(f, x0, y0, x1, y1)
+			limit := clip * float64((x1-x0)*(y1-y0)) / 256
+			excess := 0.0
+			for i, c := range h {
+				if c > limit {
+					excess += c - limit
+					h[i] = limit
+				}
+			}
+			for i := range h {
+				h[i] += excess / 256
+			}
+			luts[ty*tiles+tx] = mapping(h)
+		}
+	}
+	tileW, tileH := float64(f.W)/float64(tiles), float64(f.H)/float64(tiles)
+	out := imaging.NewFloat(f.W, f.H)
+	for y := 0; y < f.H; y++ {
+		// position relative to the tile centres
+		gy := (float64(y)+0.5)/tileH - 0.5
+		ty0 := int(math.Floor(gy))
+		ay := gy - float64(ty0)
+		ty1 := min(ty0+1, tiles-1)
+		ty0 = max(ty0, 0)
+		for x := 0; x < f.W; x++ {
+			gx := (float64(x)+0.5)/tileW - 0.5
+			tx0 := int(math.Floor(gx))
+			ax := gx - float64(tx0)
+			tx1 := min(tx0+1, tiles-1)
+			tx0 = max(tx0, 0)
+			l := level(f.At(x, y))
+			top := luts[ty0*tiles+tx0][l]*(1-ax) + luts[ty0*tiles+tx1][l]*ax
+			bottom := luts[ty1*tiles+tx0][l]*(1-ax) + luts[ty1*tiles+tx1][l]*ax
+			out.Set(x, y, top*(1-ay)+bottom*ay)
+		}
+	}
+	return out
+}
+
+// Gamma applies the power law v' = 255·(v/255)^g, brightening shadows for g < 1
+func Gamma(f *imaging.Float, g float64) *imaging.Float {
+	out := imaging.NewFloat(f.W, f.H)
+	for i, v := range f.Pix {
+		out.Pix[i] = 255 * math.Pow(math.Max(v, 0)/255, g)
+	}
+	return out
+}
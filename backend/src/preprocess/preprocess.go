@@ -0,0 +1,176 @@
+// composable preprocessing stages applied to a copy of the image before corner detection
+
+package preprocess
+
+import (
+	"Backend/src/imaging"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownStage = errors.New("unknown preprocessing stage")
+	ErrInvalidArgs  = errors.New("invalid preprocessing arguments")
+)
+
+// Stage is one preprocessing step. Args depend on the stage:
+//
+//	none                     no-op
+//	median:k                 k×k median, k odd (default 3)
+//	gaussian:sigma           gaussian blur (default 1)
+//	box:k                    k×k mean, k odd (default 3)
+//	bilateral:sigma:range    edge preserving blur, spatial and intensity sigma (default 3, 25)
+//	equalize                 global histogram equalization
+//	clahe:clip:tiles         contrast limited adaptive equalization (default 2, 8)
+//	gamma:g                  power law v' = 255·(v/255)^g (default 1)
+type Stage struct {
+	Name string
+	Args []float64
+}
+
+// defaults holds the default arguments of every stage, which also fixes how many each accepts
+var defaults = map[string][]float64{
+	"none":      {},
+	"median":    {3},
+	"gaussian":  {1},
+	"box":       {3},
+	"bilateral": {3, 25},
+	"equalize":  {},
+	"clahe":     {2, 8},
+	"gamma":     {1},
+}
+
+// Names lists the stages Parse accepts
+var Names = []string{"none", "median", "gaussian", "box", "bilateral", "equalize", "clahe", "gamma"}
+
+// Pipeline is an ordered list of stages. A nil pipeline lets the caller pick its own default, while
+// the parsed "none" pipeline explicitly disables preprocessing.
+type Pipeline []Stage
+
+// Parse reads a comma separated pipeline such as "median:5,gamma:0.8,clahe:3:8". Missing arguments
+// take the stage defaults.
+func Parse(spec string) (Pipeline, error) {
+	p := Pipeline{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Split(part, ":")
+		name := strings.ToLower(fields[0])
+		def, ok := defaults[name]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownStage, name)
+		}
+		if len(fields)-1 > len(def) {
+			return nil, fmt.Errorf("%w: %s takes at most %d", ErrInvalidArgs, name, len(def))
+		}
+		args := append([]float64(nil), def...)
+		for i, field := range fields[1:] {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s argument %q", ErrInvalidArgs, name, field)
+			}
+			args[i] = v
+		}
+		stage := Stage{Name: name, Args: args}
+		if err := stage.validate(); err != nil {
+			return nil, err
+		}
+		p = append(p, stage)
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("%w: empty pipeline", ErrInvalidArgs)
+	}
+	return p, nil
+}
+
+// MustParse is Parse for pipelines known to be valid, panicking otherwise
+func MustParse(spec string) Pipeline {
+	p, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (s Stage) validate() error {
+	odd := func(v float64) bool {
+		return v >= 1 && v == float64(int(v)) && int(v)%2 == 1
+	}
+	ok := true
+	switch s.Name {
+	case "median", "box":
+		ok = odd(s.Args[0])
+	case "gaussian", "gamma":
+		ok = s.Args[0] > 0
+	case "bilateral":
+		ok = s.Args[0] > 0 && s.Args[1] > 0
+	case "clahe":
+		ok = s.Args[0] >= 1 && s.Args[1] >= 1 && s.Args[1] == float64(int(s.Args[1]))
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidArgs, s)
+	}
+	return nil
+}
+
+func (s Stage) String() string {
+	parts := []string{s.Name}
+	for _, a := range s.Args {
+		parts = append(parts, strconv.FormatFloat(a, 'g', -1, 64))
+	}
+	return strings.Join(parts, ":")
+}
+
+// String formats the pipeline in the syntax Parse reads
+func (p Pipeline) String() string {
+	if len(p) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(p))
+	for i, s := range p {
+		parts[i] = s.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// MarshalText records the pipeline as its spec string in json and yaml metadata
+func (p Pipeline) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Pipeline) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// Apply runs the stages in order on a copy of gray, which is left untouched
+func (p Pipeline) Apply(gray *imaging.Float) *imaging.Float {
+	out := gray.Clone()
+	for _, s := range p {
+		switch s.Name {
+		case "median":
+			out = Median(out, int(s.Args[0]))
+		case "gaussian":
+			out = imaging.Gaussian(out, s.Args[0])
+		case "box":
+			out = Box(out, int(s.Args[0]))
+		case "bilateral":
+			out = Bilateral(out, s.Args[0], s.Args[1])
+		case "equalize":
+			out = Equalize(out)
+		case "clahe":
+			out = CLAHE(out, s.Args[0], int(s.Args[1]))
+		case "gamma":
+			out = Gamma(out, s.Args[0])
+		}
+	}
+	return out
+}
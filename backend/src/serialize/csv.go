@@ -0,0 +1,136 @@
+// csv corner tables with the result metadata in a leading comment
+
+package serialize
+
+import (
+	"Backend/src/corner"
+	"Backend/src/detector"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvColumns are the columns writeCSV emits. The angle is empty when it was not computed and the
+// junction angles are joined by spaces.
+var csvColumns = []string{"index", "x", "y", "score", "scale", "angle", "junction", "junction_angles"}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeCSV(w io.Writer, result *detector.Result) error {
+	meta, err := json.Marshal(headerOf(result))
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n", meta)
+	cw := csv.NewWriter(bw)
+	cw.Write(csvColumns)
+	for i, c := range result.Corners {
+		angle, junction, angles := "", "", make([]string, 0)
+		if c.HasAngle {
+			angle = formatFloat(c.Angle)
+		}
+		if c.Junction != nil {
+			junction = string(c.Junction.Type)
+			for _, a := range c.Junction.Angles {
+				angles = append(angles, formatFloat(a))
+			}
+		}
+		cw.Write([]string{
+			strconv.Itoa(i), formatFloat(c.X), formatFloat(c.Y), formatFloat(c.Score),
+			formatFloat(c.Scale), angle, junction, strings.Join(angles, " "),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// readCSV finds the columns by their header names, so any subset including x and y loads and
+// unknown columns are ignored. The "# " metadata line is optional.
+func readCSV(r io.Reader) (*detector.Result, error) {
+	br := bufio.NewReader(r)
+	var h header
+	if first, err := br.Peek(1); err == nil && first[0] == '#' {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(line[1:])), &h); err != nil {
+			return nil, fmt.Errorf("%w: header: %v", ErrSyntax, err)
+		}
+	}
+	cr := csv.NewReader(br)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	names, err := cr.Read()
+	if err == io.EOF {
+		return h.result(nil), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+	}
+	column := map[string]int{}
+	for i, name := range names {
+		column[strings.TrimSpace(name)] = i
+	}
+	if _, ok := column["x"]; !ok {
+		return nil, fmt.Errorf("%w: missing x column", ErrSyntax)
+	}
+	if _, ok := column["y"]; !ok {
+		return nil, fmt.Errorf("%w: missing y column", ErrSyntax)
+	}
+
+	var corners []corner.Corner
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+		field := func(name string) string {
+			if i, ok := column[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		var c corner.Corner
+		for name, dst := range map[string]*float64{"x": &c.X, "y": &c.Y, "score": &c.Score, "scale": &c.Scale} {
+			value := field(name)
+			if value == "" {
+				continue
+			}
+			if *dst, err = strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("%w: row %d column %s: %v", ErrSyntax, row, name, err)
+			}
+		}
+		if value := field("angle"); value != "" {
+			if c.Angle, err = strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("%w: row %d column angle: %v", ErrSyntax, row, err)
+			}
+			c.HasAngle = true
+		}
+		if junction := field("junction"); junction != "" {
+			c.Junction = &corner.Junction{Type: corner.JunctionType(junction), Angles: []float64{}}
+			for _, value := range strings.Fields(field("junction_angles")) {
+				a, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, fmt.Errorf("%w: row %d column junction_angles: %v", ErrSyntax, row, err)
+				}
+				c.Junction.Angles = append(c.Junction.Angles, a)
+			}
+		}
+		corners = append(corners, c)
+	}
+	return h.result(corners), nil
+}
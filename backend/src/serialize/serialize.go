@@ -0,0 +1,193 @@
+// writes detection results to json, csv, ndjson and opencv yaml files and reads them back
+
+package serialize
+
+import (
+	"Backend/src/corner"
+	"Backend/src/detector"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrFormat = errors.New("unknown corner format")
+	ErrSyntax = errors.New("malformed corner file")
+)
+
+type Format string
+
+const (
+	JSON   Format = "json"   // one indented detector.Result document
+	CSV    Format = "csv"    // a "# " json header line, then one row per corner
+	NDJSON Format = "ndjson" // the header object on the first line, then one corner object per line
+	YAML   Format = "yaml"   // opencv FileStorage with the corners as a KeyPoint sequence
+)
+
+// Formats lists the formats Write and Read accept
+var Formats = []Format{JSON, CSV, NDJSON, YAML}
+
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case JSON, CSV, NDJSON, YAML:
+		return Format(value), nil
+	case "yml":
+		return YAML, nil
+	case "jsonl":
+		return NDJSON, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrFormat, value)
+}
+
+// FormatFromPath picks the format from a file extension
+func FormatFromPath(filePath string) (Format, error) {
+	return ParseFormat(strings.TrimPrefix(strings.ToLower(filepath.Ext(filePath)), "."))
+}
+
+// Ext returns the file extension Save uses for the format, with its dot
+func (f Format) Ext() string {
+	if f == YAML {
+		return ".yml"
+	}
+	return "." + string(f)
+}
+
+// header is a result without its corners, the metadata the line based formats write first
+type header struct {
+	detector.Config
+	ConfigHash string `json:"config_hash,omitempty"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	BitDepth   int    `json:"bit_depth"`
+}
+
+func headerOf(result *detector.Result) header {
+	return header{Config: result.Config, ConfigHash: result.ConfigHash, Width: result.Width, Height: result.Height, BitDepth: result.BitDepth}
+}
+
+func (h header) result(corners []corner.Corner) *detector.Result {
+	if corners == nil {
+		corners = []corner.Corner{}
+	}
+	return &detector.Result{Config: h.Config, ConfigHash: h.ConfigHash, Width: h.Width, Height: h.Height, BitDepth: h.BitDepth, Corners: corners}
+}
+
+// Write encodes result in the given format
+func Write(w io.Writer, result *detector.Result, format Format) error {
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	case CSV:
+		return writeCSV(w, result)
+	case NDJSON:
+		return writeNDJSON(w, result)
+	case YAML:
+		return writeYAML(w, result)
+	}
+	return fmt.Errorf("%w %q", ErrFormat, format)
+}
+
+// Read decodes a result written by Write. Files written by other tools load too as long as they
+// follow the format: a csv needs only x and y columns and an opencv yaml only its keypoints.
+func Read(r io.Reader, format Format) (*detector.Result, error) {
+	switch format {
+	case JSON:
+		var result detector.Result
+		if err := json.NewDecoder(r).Decode(&result); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+		return &result, nil
+	case CSV:
+		return readCSV(r)
+	case NDJSON:
+		return readNDJSON(r)
+	case YAML:
+		return readYAML(r)
+	}
+	return nil, fmt.Errorf("%w %q", ErrFormat, format)
+}
+
+// Save writes result to filePath in the format its extension names
+func Save(filePath string, result *detector.Result) error {
+	format, err := FormatFromPath(filePath)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, result, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads a result from filePath in the format its extension names
+func Load(filePath string) (*detector.Result, error) {
+	format, err := FormatFromPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f, format)
+}
+
+func writeNDJSON(w io.Writer, result *detector.Result) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(headerOf(result)); err != nil {
+		return err
+	}
+	for _, c := range result.Corners {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func readNDJSON(r io.Reader) (*detector.Result, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var h header
+	var corners []corner.Corner
+	line := 0
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		line++
+		var err error
+		if line == 1 {
+			err = json.Unmarshal([]byte(text), &h)
+		} else {
+			var c corner.Corner
+			err = json.Unmarshal([]byte(text), &c)
+			corners = append(corners, c)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrSyntax, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if line == 0 {
+		return nil, fmt.Errorf("%w: missing header", ErrSyntax)
+	}
+	return h.result(corners), nil
+}
@@ -0,0 +1,119 @@
+// corners as an opencv FileStorage KeyPoint sequence
+
+package serialize
+
+import (
+	"Backend/src/corner"
+	"Backend/src/detector"
+	"Backend/src/imaging"
+	"Backend/src/preprocess"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// keypointFields is the number of values cv::write stores per KeyPoint: x, y, size, angle,
+// response, octave and class_id
+const keypointFields = 7
+
+// cvFile is the layout writeYAML produces, with the keys opencv samples use for image sizes
+type cvFile struct {
+	Width      int                `yaml:"image_width"`
+	Height     int                `yaml:"image_height"`
+	BitDepth   int                `yaml:"bit_depth"`
+	Detector   string             `yaml:"detector"`
+	Preprocess string             `yaml:"preprocess"`
+	Color      string             `yaml:"color"`
+	Params     map[string]float64 `yaml:"params"`
+	ConfigHash string             `yaml:"config_hash"`
+	Keypoints  []float64          `yaml:"keypoints"`
+}
+
+// writeYAML writes the corners the way cv::FileStorage writes a vector of KeyPoint, so
+// fs["keypoints"] >> keypoints reads them in opencv. The size is the diameter 2*scale, zero for
+// single scale detectors, and an angle of -1 marks one that was not computed, as in opencv.
+// Junctions have no KeyPoint field and are not written.
+func writeYAML(w io.Writer, result *detector.Result) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%%YAML:1.0\n---\n")
+	fmt.Fprintf(bw, "image_width: %d\nimage_height: %d\nbit_depth: %d\n", result.Width, result.Height, result.BitDepth)
+	fmt.Fprintf(bw, "detector: %q\npreprocess: %q\ncolor: %q\n", result.Detector, result.Preprocess.String(), result.Color)
+	if len(result.Params) > 0 {
+		keys := make([]string, 0, len(result.Params))
+		for k := range result.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = fmt.Sprintf("%s: %s", k, formatFloat(result.Params[k]))
+		}
+		fmt.Fprintf(bw, "params: { %s }\n", strings.Join(parts, ", "))
+	}
+	if result.ConfigHash != "" {
+		fmt.Fprintf(bw, "config_hash: %q\n", result.ConfigHash)
+	}
+	if len(result.Corners) == 0 {
+		fmt.Fprintf(bw, "keypoints: []\n")
+		return bw.Flush()
+	}
+	fmt.Fprintf(bw, "keypoints: [")
+	for i, c := range result.Corners {
+		angle := -1.0
+		if c.HasAngle {
+			angle = c.Angle
+		}
+		sep := ","
+		if i == len(result.Corners)-1 {
+			sep = " ]"
+		}
+		fmt.Fprintf(bw, "\n   %s, %s, %s, %s, %s, 0, -1%s",
+			formatFloat(c.X), formatFloat(c.Y), formatFloat(2*c.Scale), formatFloat(angle), formatFloat(c.Score), sep)
+	}
+	fmt.Fprintf(bw, "\n")
+	return bw.Flush()
+}
+
+func readYAML(r io.Reader) (*detector.Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// opencv writes its "%YAML:1.0" directive without the space yaml parsers expect
+	if bytes.HasPrefix(data, []byte("%YAML:")) {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		} else {
+			data = nil
+		}
+	}
+	var file cvFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+	}
+	if len(file.Keypoints)%keypointFields != 0 {
+		return nil, fmt.Errorf("%w: %d keypoint values is not a multiple of %d", ErrSyntax, len(file.Keypoints), keypointFields)
+	}
+	h := header{ConfigHash: file.ConfigHash, Width: file.Width, Height: file.Height, BitDepth: file.BitDepth}
+	h.Detector, h.Params, h.Color = file.Detector, file.Params, imaging.ColorSpace(file.Color)
+	if file.Preprocess != "" {
+		if h.Preprocess, err = preprocess.Parse(file.Preprocess); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+	}
+	corners := make([]corner.Corner, 0, len(file.Keypoints)/keypointFields)
+	for i := 0; i < len(file.Keypoints); i += keypointFields {
+		kp := file.Keypoints[i : i+keypointFields]
+		c := corner.Corner{X: kp[0], Y: kp[1], Scale: kp[2] / 2, Score: kp[4]}
+		if kp[3] >= 0 {
+			c.Angle, c.HasAngle = kp[3], true
+		}
+		corners = append(corners, c)
+	}
+	return h.result(corners), nil
+}
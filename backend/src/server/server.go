@@ -0,0 +1,1148 @@
+// http routes behind the corner detection ui
+
+package server
+
+import (
+	"Backend/src/annotation"
+	"Backend/src/calibration"
+	"Backend/src/chessboard"
+	"Backend/src/contour"
+	"Backend/src/corner"
+	"Backend/src/descriptor"
+	"Backend/src/detector"
+	"Backend/src/distribution"
+	"Backend/src/document"
+	"Backend/src/harrisLaplace"
+	"Backend/src/imaging"
+	"Backend/src/junction"
+	"Backend/src/metadata"
+	"Backend/src/overlay"
+	"Backend/src/pipeline"
+	"Backend/src/preprocess"
+	"Backend/src/serialize"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getLastFile(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lastEntry := entries[len(entries)-1]
+	return lastEntry.Name()
+}
+
+// detectQuery runs the detector, preprocessing and color space named by the detector, preprocess
+// and color query parameters, keeping the detector's default preprocessing when none is given
+func detectQuery(c *gin.Context, img image.Image) (*detector.Result, error) {
+	cfg, err := configQuery(c)
+	if err != nil {
+		return nil, err
+	}
+	return detector.Detect(img, cfg)
+}
+
+// configQuery reads the detector config shared by the detection routes from the query
+func configQuery(c *gin.Context) (detector.Config, error) {
+	cfg := detector.Config{Detector: c.DefaultQuery("detector", "harris")}
+	var err error
+	if cfg.Preprocess, err = parsePreprocess(c); err != nil {
+		return cfg, err
+	}
+	cfg.Color, err = imaging.ParseColorSpace(c.Query("color"))
+	return cfg, err
+}
+
+// parsePreprocess reads the optional preprocess query, returning nil for the detector default
+func parsePreprocess(c *gin.Context) (preprocess.Pipeline, error) {
+	spec := c.Query("preprocess")
+	if spec == "" {
+		return nil, nil
+	}
+	return preprocess.Parse(spec)
+}
+
+// overlayQuery reads the marker style of rendered corners from the marker, radius, min_radius,
+// size_by, line_width, marker_color, colormap, ticks, labels and antialias query parameters. The
+// color parameter selects the color space of detection, see configQuery.
+func overlayQuery(c *gin.Context) (overlay.Options, error) {
+	opts := overlay.DefaultOptions()
+	var err error
+	if value := c.Query("marker"); value != "" {
+		if opts.Marker, err = overlay.ParseMarker(value); err != nil {
+			return opts, err
+		}
+	}
+	if value := c.Query("size_by"); value != "" {
+		if opts.SizeBy, err = overlay.ParseSizeBy(value); err != nil {
+			return opts, err
+		}
+	}
+	if value := c.Query("marker_color"); value != "" {
+		if opts.Color, err = overlay.ParseColor(value); err != nil {
+			return opts, err
+		}
+	}
+	if value := c.Query("colormap"); value != "" {
+		if opts.Colormap, err = imaging.ParseColormap(value); err != nil {
+			return opts, err
+		}
+	}
+	for name, field := range map[string]*float64{"radius": &opts.Radius, "min_radius": &opts.MinRadius, "line_width": &opts.LineWidth} {
+		if value := c.Query(name); value != "" {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil || v <= 0 {
+				return opts, fmt.Errorf("%s must be a positive number", name)
+			}
+			*field = v
+		}
+	}
+	opts.Ticks = c.Query("ticks") == "true"
+	opts.Labels = c.Query("labels") == "true"
+	opts.Antialias = c.DefaultQuery("antialias", "true") == "true"
+	return opts, nil
+}
+
+// detectRoute returns the handler of a fixed detector route: it runs the named detector with its
+// defaults on the last upload and writes the marked image and the corners to outputDir
+func detectRoute(uploadsDir, outputDir, name, message string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "modified-"+name+".jpg")
+		cornersFile := filepath.Join(outputDir, "modified-"+name+serialize.JSON.Ext())
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, err := detector.Detect(img, detector.Config{Detector: name})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err := imaging.Save(corner.Draw(img, result.Corners), outputFile); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save image",
+			})
+			return
+		}
+		if err := serialize.Save(cornersFile, result); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save corners",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": message,
+			"path":    outputFile,
+			"corners": cornersFile,
+			"result":  result,
+		})
+	}
+}
+
+// New returns the router that detects corners in the files uploaded to uploadsDir and serves the
+// results it writes to outputDir
+func New(uploadsDir, outputDir string) *gin.Engine {
+	r := gin.Default()
+
+	r.Static("/output", outputDir)
+
+	// Routes
+	r.GET("/ping", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"message": "pong",
+		})
+	})
+
+	r.POST("/upload", func(c *gin.Context) {
+		if err := os.MkdirAll(uploadsDir, os.ModePerm); err != nil {
+			log.Fatal(err)
+		}
+		file, _ := c.FormFile("image")
+		log.Println(file.Filename)
+		filepath := filepath.Join(uploadsDir, file.Filename)
+
+		if err := c.SaveUploadedFile(file, filepath); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save file",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "File uploaded successfully",
+			"path":    filepath,
+		})
+
+	})
+
+	r.GET("/fast", detectRoute(uploadsDir, outputDir, "fast", "Fast jpg algorithm executed successfully"))
+	r.GET("/harris", detectRoute(uploadsDir, outputDir, "harris", "Harris Corner detection algorithm executed successfully"))
+	r.GET("/shi-tomashi", detectRoute(uploadsDir, outputDir, "shi-tomashi", "Shi Tomashi algorithm executed successfully"))
+
+	r.GET("/harris-laplace", func(c *gin.Context) {
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "modified-harris-laplace.png")
+
+		if err := harrisLaplace.HarrisLaplace(inputPath, outputFile); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Harris-Laplace corner detection algorithm executed successfully",
+			"path":    outputFile,
+		})
+	})
+
+	r.GET("/shi-tomashi-laplace", func(c *gin.Context) {
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "modified-shi-tomashi-laplace.png")
+
+		if err := harrisLaplace.ShiTomashiLaplace(inputPath, outputFile); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Shi Tomashi-Laplace algorithm executed successfully",
+			"path":    outputFile,
+		})
+	})
+
+	r.GET("/chessboard", func(c *gin.Context) {
+		cols, errCols := strconv.Atoi(c.DefaultQuery("cols", "9"))
+		rows, errRows := strconv.Atoi(c.DefaultQuery("rows", "6"))
+		if errCols != nil || errRows != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "cols and rows must be integers",
+			})
+			return
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "modified-chessboard.png")
+
+		board, err := chessboard.Chessboard(inputPath, outputFile, cols, rows)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Chessboard detected successfully",
+			"path":    outputFile,
+			"board":   board,
+		})
+	})
+
+	r.GET("/calibrate", func(c *gin.Context) {
+		cols, errCols := strconv.Atoi(c.DefaultQuery("cols", "9"))
+		rows, errRows := strconv.Atoi(c.DefaultQuery("rows", "6"))
+		square, errSquare := strconv.ParseFloat(c.DefaultQuery("square", "1"), 64)
+		if errCols != nil || errRows != nil || errSquare != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "cols, rows and square must be numbers",
+			})
+			return
+		}
+		entries, err := os.ReadDir(uploadsDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		paths := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			paths = append(paths, filepath.Join(uploadsDir, entry.Name()))
+		}
+
+		result, skipped, err := calibration.CalibrateImages(paths, cols, rows, square, chessboard.DefaultOptions(), calibration.DefaultOptions())
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   err.Error(),
+				"skipped": skipped,
+			})
+			return
+		}
+		if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+			log.Fatal(err)
+		}
+		jsonFile := filepath.Join(outputDir, "calibration.json")
+		yamlFile := filepath.Join(outputDir, "calibration.yml")
+		if err := result.Save(jsonFile); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save calibration",
+			})
+			return
+		}
+		if err := result.Save(yamlFile); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save calibration",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":     "Camera calibrated successfully",
+			"path":        jsonFile,
+			"yaml":        yamlFile,
+			"calibration": result,
+			"skipped":     skipped,
+		})
+	})
+
+	r.GET("/undistort", func(c *gin.Context) {
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "undistorted.png")
+
+		if err := calibration.UndistortFile(inputPath, outputFile, filepath.Join(outputDir, "calibration.json")); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Image undistorted successfully",
+			"path":    outputFile,
+		})
+	})
+
+	r.GET("/document", func(c *gin.Context) {
+		aspect, err := document.ParseAspect(c.Query("aspect"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "aspect must be auto, a4, letter or a number",
+			})
+			return
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "rectified-document.png")
+
+		quad, err := document.Document(inputPath, outputFile, aspect)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Document rectified successfully",
+			"path":    outputFile,
+			"quad":    quad,
+		})
+	})
+
+	r.GET("/edges", func(c *gin.Context) {
+		opts := imaging.DefaultLineOptions()
+		opts.Probabilistic = c.Query("mode") == "probabilistic"
+		var errs [4]error
+		opts.Sigma, errs[0] = strconv.ParseFloat(c.DefaultQuery("sigma", strconv.FormatFloat(opts.Sigma, 'g', -1, 64)), 64)
+		opts.Low, errs[1] = strconv.ParseFloat(c.DefaultQuery("low", strconv.FormatFloat(opts.Low, 'g', -1, 64)), 64)
+		opts.High, errs[2] = strconv.ParseFloat(c.DefaultQuery("high", strconv.FormatFloat(opts.High, 'g', -1, 64)), 64)
+		opts.Threshold, errs[3] = strconv.Atoi(c.DefaultQuery("threshold", strconv.Itoa(opts.Threshold)))
+		for _, err := range errs {
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "sigma, low, high and threshold must be numbers",
+				})
+				return
+			}
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		edgesFile := filepath.Join(outputDir, "edges-canny.png")
+		linesFile := filepath.Join(outputDir, "lines-hough.png")
+
+		corners, err := imaging.DetectLines(inputPath, edgesFile, linesFile, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Canny edges and Hough lines computed successfully",
+			"edges":   edgesFile,
+			"path":    linesFile,
+			"corners": corners,
+		})
+	})
+
+	r.GET("/contours", func(c *gin.Context) {
+		opts := contour.DefaultOptions()
+		var errThreshold, errSigma error
+		opts.Threshold, errThreshold = strconv.ParseFloat(c.DefaultQuery("threshold", "0"), 64)
+		opts.SigmaHigh, errSigma = strconv.ParseFloat(c.DefaultQuery("sigma", strconv.FormatFloat(opts.SigmaHigh, 'g', -1, 64)), 64)
+		if errThreshold != nil || errSigma != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "threshold and sigma must be numbers",
+			})
+			return
+		}
+		switch c.Query("foreground") {
+		case "dark":
+			opts.Foreground = contour.Dark
+		case "light":
+			opts.Foreground = contour.Light
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "modified-contours.png")
+
+		contours, corners, err := contour.Contours(inputPath, outputFile, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		polygons := make(map[int][]image.Point)
+		for _, ct := range contours {
+			if ct.Polygon != nil {
+				polygons[ct.ID] = ct.Polygon
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "Contour corner detection executed successfully",
+			"path":     outputFile,
+			"corners":  corners,
+			"polygons": polygons,
+		})
+	})
+
+	r.GET("/detect", func(c *gin.Context) {
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "modified-detect.png")
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if c.Query("debug") == "true" {
+			format := c.DefaultQuery("debug_format", "png")
+			if format != "png" && format != "tiff" {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "debug_format must be png or tiff",
+				})
+				return
+			}
+			cfg, err := configQuery(c)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			result, maps, err := detector.Debug(img, cfg)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			bundleFile := filepath.Join(outputDir, "debug.zip")
+			f, err := os.Create(bundleFile)
+			if err == nil {
+				err = detector.WriteBundle(f, result, maps, format)
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
+				}
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to save debug bundle",
+				})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"message": "Debug bundle created successfully",
+				"path":    bundleFile,
+				"maps":    maps.Names,
+				"result":  result,
+			})
+			return
+		}
+
+		style, err := overlayQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, err := detectQuery(c, img)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		rendered := overlay.Render(img, result.Corners, style)
+		if c.Query("embed") == "true" {
+			// the png carries the result as a text chunk, so it survives leaving the server
+			err = metadata.Save(rendered, outputFile, result)
+		} else {
+			err = imaging.Save(rendered, outputFile)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save image",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Corners detected successfully",
+			"path":    outputFile,
+			"result":  result,
+		})
+	})
+
+	r.GET("/junctions", func(c *gin.Context) {
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "modified-junctions.png")
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, err := detectQuery(c, img)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		corners := result.Corners
+		junction.Annotate(imaging.ToFloat(img), corners, junction.DefaultOptions())
+		if err := imaging.Save(corner.Draw(img, corners), outputFile); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save image",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Junctions classified successfully",
+			"path":       outputFile,
+			"corners":    corners,
+			"preprocess": result.Preprocess,
+		})
+	})
+
+	r.GET("/patches", func(c *gin.Context) {
+		opts := descriptor.DefaultOptions()
+		size, errSize := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(opts.Size)))
+		limit, errLimit := strconv.Atoi(c.DefaultQuery("max", "256"))
+		if errSize != nil || errLimit != nil || size < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "size and max must be positive integers",
+			})
+			return
+		}
+		opts.Size = size
+		if c.Query("orientation") == "histogram" {
+			opts.Method = descriptor.Histogram
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		mosaicFile := filepath.Join(outputDir, "patches.png")
+		arrayFile := filepath.Join(outputDir, "patches.npy")
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, err := detectQuery(c, img)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		corners := result.Corners
+		sort.Slice(corners, func(i, j int) bool {
+			return corners[i].Score > corners[j].Score
+		})
+		if len(corners) > limit {
+			corners = corners[:limit]
+		}
+		patches := descriptor.Describe(imaging.ToFloat(img), corners, opts)
+
+		if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+			log.Fatal(err)
+		}
+		if err := imaging.Save(descriptor.Mosaic(patches, 16), mosaicFile); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save patches",
+			})
+			return
+		}
+		f, err := os.Create(arrayFile)
+		if err == nil {
+			err = descriptor.WriteNPY(f, patches)
+			f.Close()
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save patches",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Patches extracted successfully",
+			"path":       mosaicFile,
+			"array":      arrayFile,
+			"corners":    corners,
+			"preprocess": result.Preprocess,
+		})
+	})
+
+	r.GET("/distribute", func(c *gin.Context) {
+		count, err := strconv.Atoi(c.DefaultQuery("count", "500"))
+		if err != nil || count < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "count must be a positive integer",
+			})
+			return
+		}
+		style, err := overlayQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "modified-distributed.png")
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, err := detectQuery(c, img)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		corners := result.Corners
+		bounds := img.Bounds()
+		corners, err = distribution.Apply(c.DefaultQuery("strategy", "anms"), corners, bounds.Dx(), bounds.Dy(), count)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err := imaging.Save(overlay.Render(img, corners, style), outputFile); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save image",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Corners distributed successfully",
+			"path":       outputFile,
+			"corners":    corners,
+			"preprocess": result.Preprocess,
+		})
+	})
+
+	r.GET("/auto-threshold", func(c *gin.Context) {
+		opts := detector.DefaultAutoOptions()
+		var errs [3]error
+		opts.Target, errs[0] = strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(opts.Target)))
+		opts.TilesX, errs[1] = strconv.Atoi(c.DefaultQuery("tiles_x", c.DefaultQuery("tiles", "1")))
+		opts.TilesY, errs[2] = strconv.Atoi(c.DefaultQuery("tiles_y", c.DefaultQuery("tiles", "1")))
+		for _, err := range errs {
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "count, tiles, tiles_x and tiles_y must be integers",
+				})
+				return
+			}
+		}
+		style, err := overlayQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "modified-auto-threshold.png")
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		cfg, err := configQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, selection, err := detector.AutoThreshold(img, cfg, opts)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err := imaging.Save(overlay.Render(img, result.Corners, style), outputFile); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save image",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Threshold selected successfully",
+			"path":       outputFile,
+			"selection":  selection,
+			"corners":    result.Corners,
+			"preprocess": result.Preprocess,
+		})
+	})
+
+	r.GET("/heatmap", func(c *gin.Context) {
+		opts := imaging.DefaultHeatmapOptions()
+		var err error
+		if opts.Colormap, err = imaging.ParseColormap(c.Query("colormap")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		opts.Alpha, err = strconv.ParseFloat(c.DefaultQuery("alpha", strconv.FormatFloat(opts.Alpha, 'g', -1, 64)), 64)
+		if err != nil || opts.Alpha < 0 || opts.Alpha > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "alpha must be a number between 0 and 1",
+			})
+			return
+		}
+		opts.Log = c.DefaultQuery("scale", "log") != "linear"
+		cfg, err := configQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "modified-heatmap.png")
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, maps, err := detector.Debug(img, cfg)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		response, err := detector.Response(cfg.Detector, maps)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err := imaging.Save(imaging.Heatmap(img, response, opts), outputFile); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save image",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Heatmap rendered successfully",
+			"path":    outputFile,
+			"corners": result.Corners,
+		})
+	})
+
+	r.GET("/svg", func(c *gin.Context) {
+		style, err := overlayQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "modified-overlay.svg")
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, err := detectQuery(c, img)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		svg := overlay.SVGOptions{Detector: result.Detector}
+		switch c.DefaultQuery("image", "embed") {
+		case "embed":
+			svg.Image = img
+		case "reference":
+			// only outputDir is served, so the svg references a copy of the input next to it
+			name := "modified-overlay-image" + filepath.Ext(lastEntry)
+			data, err := os.ReadFile(inputPath)
+			if err == nil {
+				err = os.WriteFile(filepath.Join(outputDir, name), data, 0o644)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to copy the image the svg references",
+				})
+				return
+			}
+			svg.ImageHref = name
+		case "none":
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "image must be embed, reference or none",
+			})
+			return
+		}
+		f, err := os.Create(outputFile)
+		if err == nil {
+			err = overlay.WriteSVG(f, result.Corners, result.Width, result.Height, style, svg)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save svg",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Svg overlay created successfully",
+			"path":    outputFile,
+			"corners": result.Corners,
+		})
+	})
+
+	r.GET("/export", func(c *gin.Context) {
+		format, err := serialize.ParseFormat(c.DefaultQuery("format", "json"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "corners"+format.Ext())
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, err := detectQuery(c, img)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err := serialize.Save(outputFile, result); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save corners",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Corners exported successfully",
+			"path":    outputFile,
+			"format":  format,
+			"count":   len(result.Corners),
+		})
+	})
+
+	r.GET("/annotations", func(c *gin.Context) {
+		format, err := annotation.ParseFormat(c.DefaultQuery("format", "coco"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		outputFile := filepath.Join(outputDir, "annotations-"+string(format)+format.Ext())
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, err := detectQuery(c, img)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		images := []annotation.Image{annotation.FromResult(lastEntry, result)}
+		if err := annotation.Save(outputFile, images, format); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save annotations",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Annotations exported successfully",
+			"path":    outputFile,
+			"format":  format,
+			"count":   len(result.Corners),
+		})
+	})
+
+	// evaluate scores the detector on the last upload against ground truth points posted as a coco,
+	// labelme or cvat file in the truth form field
+	r.POST("/evaluate", func(c *gin.Context) {
+		tolerance, err := strconv.ParseFloat(c.DefaultQuery("tolerance", "3"), 64)
+		if err != nil || tolerance < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "tolerance must be a non negative number",
+			})
+			return
+		}
+		header, err := c.FormFile("truth")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "truth file is required",
+			})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		images, format, err := annotation.Read(file)
+		file.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+		truth, ok := annotation.Find(images, lastEntry)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("no annotations for %s", lastEntry),
+			})
+			return
+		}
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, err := detectQuery(c, img)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Detection evaluated successfully",
+			"format":     format,
+			"evaluation": annotation.Evaluate(result.Corners, truth.Points, tolerance),
+		})
+	})
+
+	// metadata returns the detection result embedded in a posted png or jpeg
+	r.POST("/metadata", func(c *gin.Context) {
+		header, err := c.FormFile("image")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "image file is required",
+			})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		result, err := metadata.Extract(data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Metadata extracted successfully",
+			"result":  result,
+		})
+	})
+
+	// pipeline runs a posted yaml or json pipeline spec on the last uploaded image
+	r.POST("/pipeline", func(c *gin.Context) {
+		header, err := c.FormFile("spec")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "spec file is required",
+			})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		spec, err := pipeline.Parse(data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		lastEntry := getLastFile(uploadsDir)
+		inputPath := filepath.Join(uploadsDir, lastEntry)
+
+		img, err := imaging.Load(inputPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		detections, written, err := spec.Execute(img, inputPath, outputDir)
+		if errors.Is(err, pipeline.ErrOutputPath) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":     "Pipeline executed successfully",
+			"paths":       written,
+			"config_hash": spec.Hash(),
+			"detections":  detections,
+		})
+	})
+
+	return r
+}
@@ -0,0 +1,85 @@
+// repeatability against synthetic warps with a known homography
+
+package sweep
+
+import (
+	"Backend/src/annotation"
+	"Backend/src/corner"
+	"Backend/src/geometry"
+	"Backend/src/imaging"
+	"image"
+	"math"
+)
+
+// margin keeps corners this close to an image border, or to the black edge a warp leaves, out of
+// the repeatability count, since the edge itself makes corners that have no counterpart
+const margin = 8
+
+// Image is a dataset image together with a warped copy and the homography mapping it onto the copy
+type Image struct {
+	Path   string
+	Image  image.Image
+	Warped image.Image
+	H      geometry.Homography
+}
+
+// Transform returns the homography rotating by degrees and scaling about the center of a width x
+// height image
+func Transform(width, height int, degrees, scale float64) geometry.Homography {
+	cx, cy := float64(width)/2, float64(height)/2
+	a := degrees * math.Pi / 180
+	c, s := scale*math.Cos(a), scale*math.Sin(a)
+	return geometry.Homography{
+		c, -s, cx - c*cx + s*cy,
+		s, c, cy - s*cx - c*cy,
+		0, 0, 1,
+	}
+}
+
+// NewImage pairs img with a copy of the same size warped as opts describes
+func NewImage(path string, img image.Image, opts Options) (Image, error) {
+	b := img.Bounds()
+	h := Transform(b.Dx(), b.Dy(), opts.Rotate, opts.Scale)
+	inv, err := h.Inverse()
+	if err != nil {
+		return Image{}, err
+	}
+	warped := imaging.Warp(img, b.Dx(), b.Dy(), func(x, y float64) (float64, float64) {
+		p := inv.Apply(geometry.Point{X: x, Y: y})
+		return p.X, p.Y
+	})
+	return Image{Path: path, Image: img, Warped: warped, H: h}, nil
+}
+
+// repeatability is the share of corners seen in both images that are detected in both, within
+// tolerance once the warped corners are mapped back: matched / min(found, warped found), counting
+// only corners whose position lies well inside both images
+func (img Image) repeatability(found, warped []corner.Corner, tolerance float64) float64 {
+	inv, err := img.H.Inverse()
+	if err != nil {
+		return 0
+	}
+	b := img.Image.Bounds()
+	inside := func(p geometry.Point) bool {
+		return p.X >= margin && p.Y >= margin && p.X < float64(b.Dx()-margin) && p.Y < float64(b.Dy()-margin)
+	}
+	var a, w []corner.Corner
+	for _, c := range found {
+		p := geometry.Point{X: c.X, Y: c.Y}
+		if inside(p) && inside(img.H.Apply(p)) {
+			a = append(a, c)
+		}
+	}
+	for _, c := range warped {
+		q := geometry.Point{X: c.X, Y: c.Y}
+		if p := inv.Apply(q); inside(q) && inside(p) {
+			c.X, c.Y = p.X, p.Y
+			w = append(w, c)
+		}
+	}
+	if len(a) == 0 || len(w) == 0 {
+		return 0
+	}
+	matched := annotation.Evaluate(w, a, tolerance).Matched
+	return float64(matched) / math.Min(float64(len(a)), float64(len(w)))
+}
@@ -0,0 +1,442 @@
+// parameter sweeps: runs a detector over a dataset for every configuration of a grid or a random
+// search, scores each one and ranks them, marking the pareto front of the chosen metrics
+
+package sweep
+
+import (
+	"Backend/src/detector"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrParam   = errors.New("invalid sweep parameter")
+	ErrMetric  = errors.New("unknown metric")
+	ErrNoGrid  = errors.New("a range without a step has no grid, give a step or use a random search")
+	ErrNoParam = errors.New("no parameters to sweep")
+)
+
+type Metric string
+
+const (
+	Repeatability Metric = "repeatability" // share of corners found again in a warped copy, higher is better
+	Count         Metric = "count"         // mean corners per image, higher is better or closest to a target
+	Runtime       Metric = "runtime"       // mean detection time per image, lower is better
+)
+
+// Metrics lists the metrics ParseMetrics accepts
+var Metrics = []Metric{Repeatability, Count, Runtime}
+
+// ParseMetrics reads a comma separated list of metrics, in ranking order
+func ParseMetrics(value string) ([]Metric, error) {
+	var metrics []Metric
+	seen := map[Metric]bool{}
+	for _, part := range strings.Split(value, ",") {
+		m := Metric(strings.TrimSpace(part))
+		switch m {
+		case Repeatability, Count, Runtime:
+		default:
+			return nil, fmt.Errorf("%w %q", ErrMetric, m)
+		}
+		if !seen[m] {
+			metrics = append(metrics, m)
+			seen[m] = true
+		}
+	}
+	return metrics, nil
+}
+
+// Param is a detector param to sweep. Values are its grid; a continuous range from Min to Max has
+// none and can only be sampled by a random search.
+type Param struct {
+	Name   string
+	Values []float64
+	Min    float64
+	Max    float64
+}
+
+// ParseParam reads name=min:max:step for a stepped range, name=min:max for a continuous one or
+// name=a,b,c for a list of values
+func ParseParam(spec string) (Param, error) {
+	name, values, ok := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || values == "" {
+		return Param{}, fmt.Errorf("%w %q, expected name=min:max:step, name=min:max or name=a,b,c", ErrParam, spec)
+	}
+	p := Param{Name: name}
+	number := func(s string) (float64, error) {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, fmt.Errorf("%w %q: %q is not a number", ErrParam, spec, s)
+		}
+		return v, nil
+	}
+	if strings.Contains(values, ":") {
+		parts := strings.Split(values, ":")
+		if len(parts) > 3 {
+			return Param{}, fmt.Errorf("%w %q: a range is min:max or min:max:step", ErrParam, spec)
+		}
+		bounds := make([]float64, len(parts))
+		for i, s := range parts {
+			v, err := number(s)
+			if err != nil {
+				return Param{}, err
+			}
+			bounds[i] = v
+		}
+		p.Min, p.Max = bounds[0], bounds[1]
+		if p.Max < p.Min {
+			return Param{}, fmt.Errorf("%w %q: max is below min", ErrParam, spec)
+		}
+		if len(bounds) == 3 {
+			step := bounds[2]
+			if step <= 0 {
+				return Param{}, fmt.Errorf("%w %q: step must be positive", ErrParam, spec)
+			}
+			// multiplying rather than accumulating keeps 0.1 steps from drifting past max
+			for i := 0; ; i++ {
+				v := p.Min + float64(i)*step
+				if v > p.Max+step*1e-9 {
+					break
+				}
+				p.Values = append(p.Values, roundTo(v, step))
+			}
+		}
+		return p, nil
+	}
+	for _, s := range strings.Split(values, ",") {
+		v, err := number(s)
+		if err != nil {
+			return Param{}, err
+		}
+		p.Values = append(p.Values, v)
+	}
+	sort.Float64s(p.Values)
+	p.Min, p.Max = p.Values[0], p.Values[len(p.Values)-1]
+	return p, nil
+}
+
+// roundTo drops the floating point noise of v below the precision of step
+func roundTo(v, step float64) float64 {
+	digits := math.Max(0, 6-math.Floor(math.Log10(step)))
+	scale := math.Pow(10, digits)
+	return math.Round(v*scale) / scale
+}
+
+// Grid returns every combination of the param values, the last param varying fastest
+func Grid(params []Param) ([]detector.Params, error) {
+	if len(params) == 0 {
+		return nil, ErrNoParam
+	}
+	configs := []detector.Params{{}}
+	for _, p := range params {
+		if len(p.Values) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNoGrid, p.Name)
+		}
+		next := make([]detector.Params, 0, len(configs)*len(p.Values))
+		for _, c := range configs {
+			for _, v := range p.Values {
+				n := detector.Params{p.Name: v}
+				for k, cv := range c {
+					n[k] = cv
+				}
+				next = append(next, n)
+			}
+		}
+		configs = next
+	}
+	return configs, nil
+}
+
+// Random draws up to n distinct combinations, picking grid values uniformly and continuous ranges
+// uniformly between their bounds, rounded to four significant digits. Fewer are returned when the
+// grid has fewer combinations.
+func Random(params []Param, n int, seed int64) ([]detector.Params, error) {
+	if len(params) == 0 {
+		return nil, ErrNoParam
+	}
+	rng := rand.New(rand.NewSource(seed))
+	seen := map[string]bool{}
+	var configs []detector.Params
+	// a small grid runs out of new combinations, so give up after many repeats
+	for misses := 0; len(configs) < n && misses < 100*n; {
+		c := detector.Params{}
+		for _, p := range params {
+			if len(p.Values) > 0 {
+				c[p.Name] = p.Values[rng.Intn(len(p.Values))]
+			} else {
+				c[p.Name], _ = strconv.ParseFloat(strconv.FormatFloat(p.Min+rng.Float64()*(p.Max-p.Min), 'g', 4, 64), 64)
+			}
+		}
+		key := fmt.Sprint(c)
+		if seen[key] {
+			misses++
+			continue
+		}
+		seen[key] = true
+		configs = append(configs, c)
+	}
+	return configs, nil
+}
+
+type Options struct {
+	Config    detector.Config // detector, preprocessing and fixed params, overridden by the swept ones
+	Params    []Param
+	Samples   int   // configurations drawn by a random search, 0 for the full grid
+	Seed      int64 // random search seed
+	Metrics   []Metric
+	Target    int     // when positive the best count is the one closest to it
+	Tolerance float64 // largest distance in pixels at which a corner counts as repeated
+	Rotate    float64 // rotation in degrees of the warped copies used for repeatability
+	Scale     float64 // scale of the warped copies used for repeatability
+	Runs      int     // timed detections per image, the fastest is kept
+	Process   func(Trial)
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Config:    detector.Config{Detector: "harris"},
+		Metrics:   []Metric{Repeatability, Count, Runtime},
+		Tolerance: 2,
+		Rotate:    10,
+		Scale:     0.9,
+		Runs:      1,
+	}
+}
+
+// Trial is the score of one configuration over the dataset. Repeatability is only measured when it
+// is one of the metrics.
+type Trial struct {
+	Params        detector.Params `json:"params"`
+	Count         float64         `json:"count"`
+	Repeatability float64         `json:"repeatability"`
+	Millis        float64         `json:"millis"`
+	Rank          int             `json:"rank"`
+	Pareto        bool            `json:"pareto"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// Run scores every configuration of the search on the images, one at a time so the runtimes are
+// comparable, then ranks them. A param the detector does not know fails the whole sweep up front.
+// Cancelling ctx ranks the trials finished so far and returns them with the context error.
+func Run(ctx context.Context, images []Image, opts Options) ([]Trial, error) {
+	var configs []detector.Params
+	var err error
+	if opts.Samples > 0 {
+		configs, err = Random(opts.Params, opts.Samples, opts.Seed)
+	} else {
+		configs, err = Grid(opts.Params)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range opts.Params {
+		cfg := opts.Config
+		cfg.Params = detector.Params{p.Name: p.Min}
+		if err := detector.Validate(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Metrics) == 0 {
+		opts.Metrics = DefaultOptions().Metrics
+	}
+	if opts.Runs < 1 {
+		opts.Runs = 1
+	}
+	measure := false
+	for _, m := range opts.Metrics {
+		measure = measure || m == Repeatability
+	}
+
+	trials := make([]Trial, 0, len(configs))
+	for _, params := range configs {
+		if ctx.Err() != nil {
+			break
+		}
+		cfg := opts.Config
+		cfg.Params = detector.Params{}
+		for k, v := range opts.Config.Params {
+			cfg.Params[k] = v
+		}
+		for k, v := range params {
+			cfg.Params[k] = v
+		}
+		t := score(images, cfg, measure, opts)
+		t.Params = params
+		trials = append(trials, t)
+		if opts.Process != nil {
+			opts.Process(t)
+		}
+	}
+	Rank(trials, opts.Metrics, opts.Target)
+	return trials, ctx.Err()
+}
+
+// score runs one configuration on every image. A detector error or panic fails the trial.
+func score(images []Image, cfg detector.Config, measure bool, opts Options) (t Trial) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Error = fmt.Sprint("panic: ", r)
+		}
+	}()
+	if err := detector.Validate(cfg); err != nil {
+		t.Error = err.Error()
+		return t
+	}
+	for _, img := range images {
+		var result *detector.Result
+		best := math.Inf(1)
+		for i := 0; i < opts.Runs; i++ {
+			start := time.Now()
+			r, err := detector.Detect(img.Image, cfg)
+			elapsed := float64(time.Since(start).Microseconds()) / 1000
+			if err != nil {
+				t.Error = fmt.Sprintf("%s: %v", img.Path, err)
+				return t
+			}
+			result, best = r, math.Min(best, elapsed)
+		}
+		t.Millis += best
+		t.Count += float64(len(result.Corners))
+		if measure {
+			warped, err := detector.Detect(img.Warped, cfg)
+			if err != nil {
+				t.Error = fmt.Sprintf("%s: %v", img.Path, err)
+				return t
+			}
+			t.Repeatability += img.repeatability(result.Corners, warped.Corners, opts.Tolerance)
+		}
+	}
+	n := float64(len(images))
+	t.Millis, t.Count, t.Repeatability = t.Millis/n, t.Count/n, t.Repeatability/n
+	return t
+}
+
+// objective returns a metric of t oriented so that larger is better
+func objective(t Trial, m Metric, target int) float64 {
+	switch m {
+	case Repeatability:
+		return t.Repeatability
+	case Count:
+		if target > 0 {
+			return -math.Abs(t.Count - float64(target))
+		}
+		return t.Count
+	}
+	return -t.Millis
+}
+
+// Rank orders the trials by the metrics, the first deciding unless tied, and marks the ones no
+// other trial beats on every metric. Failed trials come last and are never on the front.
+func Rank(trials []Trial, metrics []Metric, target int) {
+	sort.SliceStable(trials, func(i, j int) bool {
+		a, b := trials[i], trials[j]
+		if (a.Error == "") != (b.Error == "") {
+			return a.Error == ""
+		}
+		for _, m := range metrics {
+			if va, vb := objective(a, m, target), objective(b, m, target); va != vb {
+				return va > vb
+			}
+		}
+		return false
+	})
+	for i := range trials {
+		trials[i].Rank = i + 1
+		trials[i].Pareto = trials[i].Error == ""
+		for j := range trials {
+			if trials[i].Pareto && j != i && trials[j].Error == "" && dominates(trials[j], trials[i], metrics, target) {
+				trials[i].Pareto = false
+			}
+		}
+	}
+}
+
+// dominates tells whether a is at least as good as b on every metric and better on one
+func dominates(a, b Trial, metrics []Metric, target int) bool {
+	better := false
+	for _, m := range metrics {
+		va, vb := objective(a, m, target), objective(b, m, target)
+		if va < vb {
+			return false
+		}
+		better = better || va > vb
+	}
+	return better
+}
+
+// Names returns the swept param names in column order
+func Names(params []Param) []string {
+	names := make([]string, len(params))
+	for i, p := range params {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// WriteCSV writes the ranked trials, one row each with a column per swept param. Repeatability is
+// left empty when it was not measured.
+func WriteCSV(w io.Writer, trials []Trial, names []string, metrics []Metric) error {
+	measured := false
+	for _, m := range metrics {
+		measured = measured || m == Repeatability
+	}
+	format := func(v float64, prec int) string {
+		return strconv.FormatFloat(v, 'f', prec, 64)
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(append(append([]string{"rank"}, names...), "count", "repeatability", "runtime_ms", "pareto", "error"))
+	for _, t := range trials {
+		row := []string{strconv.Itoa(t.Rank)}
+		for _, name := range names {
+			row = append(row, strconv.FormatFloat(t.Params[name], 'g', -1, 64))
+		}
+		repeatability := ""
+		if measured && t.Error == "" {
+			repeatability = format(t.Repeatability, 4)
+		}
+		row = append(row, format(t.Count, 1), repeatability, format(t.Millis, 3), strconv.FormatBool(t.Pareto), t.Error)
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Summary is the pareto front of a sweep together with what was swept and how it was scored
+type Summary struct {
+	Config  detector.Config `json:"config"`
+	Metrics []Metric        `json:"metrics"`
+	Target  int             `json:"target,omitempty"`
+	Images  int             `json:"images"`
+	Trials  int             `json:"trials"`
+	Failed  int             `json:"failed"`
+	Best    *Trial          `json:"best,omitempty"`
+	Pareto  []Trial         `json:"pareto"`
+}
+
+// Summarize collects the pareto front of ranked trials, best ranked first
+func Summarize(trials []Trial, images int, opts Options) Summary {
+	s := Summary{Config: opts.Config, Metrics: opts.Metrics, Target: opts.Target, Images: images, Trials: len(trials), Pareto: []Trial{}}
+	for _, t := range trials {
+		if t.Error != "" {
+			s.Failed++
+		}
+		if t.Pareto {
+			s.Pareto = append(s.Pareto, t)
+		}
+	}
+	if len(trials) > 0 && trials[0].Error == "" {
+		best := trials[0]
+		s.Best = &best
+	}
+	return s
+}
@@ -0,0 +1,131 @@
+// follows corners from frame to frame with pyramidal lucas-kanade optical flow
+
+package tracking
+
+import (
+	"Backend/src/corner"
+	"Backend/src/imaging"
+	"math"
+)
+
+type Options struct {
+	Window     int     // side of the window matched around each corner, odd
+	Levels     int     // pyramid levels above the full resolution frame
+	Iterations int     // most gauss-newton steps per level
+	Epsilon    float64 // stop iterating once a step moves less than this many pixels
+	MinEigen   float64 // lose corners whose window gradient tensor has a smaller eigenvalue per pixel
+	MaxError   float64 // lose corners whose mean absolute gray difference exceeds this, 0 for no limit
+}
+
+func DefaultOptions() Options {
+	return Options{Window: 21, Levels: 3, Iterations: 20, Epsilon: 0.01, MinEigen: 1e-2, MaxError: 30}
+}
+
+// Pyramid holds a frame at halving resolutions with the derivatives of every level, so each frame
+// of a sequence is prepared once whether it is tracked from or to
+type Pyramid struct {
+	levels []*imaging.Float
+	dx, dy []*imaging.Float
+}
+
+// NewPyramid builds up to levels halvings of f, stopping before a level gets smaller than a window
+func NewPyramid(f *imaging.Float, opts Options) *Pyramid {
+	p := &Pyramid{}
+	for level := f; ; {
+		dx, dy := imaging.Derivatives(level)
+		p.levels, p.dx, p.dy = append(p.levels, level), append(p.dx, dx), append(p.dy, dy)
+		if len(p.levels) > opts.Levels || min(level.W, level.H)/2 < opts.Window {
+			break
+		}
+		level = imaging.Downsample(imaging.Gaussian(level, 1), 2)
+	}
+	return p
+}
+
+// Point is where a corner went in the next frame. Corners that were lost keep their last position.
+type Point struct {
+	corner.Corner
+	Found bool    `json:"found"`
+	Error float64 `json:"error"` // mean absolute gray difference of the matched windows
+}
+
+// Track finds every corner of the prev frame in the next one. The displacement is estimated on the
+// coarsest level and refined level by level, so motions larger than the window are followed.
+func Track(prev, next *Pyramid, corners []corner.Corner, opts Options) []Point {
+	points := make([]Point, len(corners))
+	top := min(len(prev.levels), len(next.levels)) - 1
+	half := opts.Window / 2
+	n := float64((2*half + 1) * (2*half + 1))
+	for i, c := range corners {
+		points[i] = Point{Corner: c}
+		var gx, gy float64
+		lost := false
+		for level := top; level >= 0 && !lost; level-- {
+			scale := math.Ldexp(1, -level)
+			// block averaging puts level pixel centers at the middle of their blocks
+			px, py := (c.X+0.5)*scale-0.5, (c.Y+0.5)*scale-0.5
+			img, dx, dy := prev.levels[level], prev.dx[level], prev.dy[level]
+			target := next.levels[level]
+
+			var gxx, gyy, gxy float64
+			for j := -half; j <= half; j++ {
+				for k := -half; k <= half; k++ {
+					ix, iy := dx.Bilinear(px+float64(k), py+float64(j)), dy.Bilinear(px+float64(k), py+float64(j))
+					gxx += ix * ix
+					gyy += iy * iy
+					gxy += ix * iy
+				}
+			}
+			det := gxx*gyy - gxy*gxy
+			trace := gxx + gyy
+			minEigen := (trace - math.Sqrt(math.Max(trace*trace-4*det, 0))) / 2
+			if minEigen/n < opts.MinEigen || det == 0 {
+				lost = true
+				break
+			}
+
+			var vx, vy float64
+			for it := 0; it < opts.Iterations; it++ {
+				var bx, by float64
+				for j := -half; j <= half; j++ {
+					for k := -half; k <= half; k++ {
+						x, y := px+float64(k), py+float64(j)
+						diff := img.Bilinear(x, y) - target.Bilinear(x+gx+vx, y+gy+vy)
+						bx += diff * dx.Bilinear(x, y)
+						by += diff * dy.Bilinear(x, y)
+					}
+				}
+				ex := (gyy*bx - gxy*by) / det
+				ey := (gxx*by - gxy*bx) / det
+				vx, vy = vx+ex, vy+ey
+				if math.Hypot(ex, ey) < opts.Epsilon {
+					break
+				}
+			}
+			gx, gy = gx+vx, gy+vy
+			if level > 0 {
+				gx, gy = 2*gx, 2*gy
+			}
+		}
+		if lost {
+			continue
+		}
+		x, y := c.X+gx, c.Y+gy
+		full := next.levels[0]
+		if x < 0 || y < 0 || x > float64(full.W-1) || y > float64(full.H-1) {
+			continue
+		}
+		var residual float64
+		for j := -half; j <= half; j++ {
+			for k := -half; k <= half; k++ {
+				residual += math.Abs(prev.levels[0].Bilinear(c.X+float64(k), c.Y+float64(j)) - full.Bilinear(x+float64(k), y+float64(j)))
+			}
+		}
+		points[i].Error = residual / n
+		if opts.MaxError > 0 && points[i].Error > opts.MaxError {
+			continue
+		}
+		points[i].X, points[i].Y, points[i].Found = x, y, true
+	}
+	return points
+}
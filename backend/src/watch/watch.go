@@ -0,0 +1,386 @@
+// polls a folder for new images and runs detectors on them as they arrive, keeping state so a
+// restarted watcher skips the files it already processed
+
+package watch
+
+import (
+	"Backend/src/batch"
+	"Backend/src/detector"
+	"Backend/src/serialize"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoDetectors       = errors.New("no detectors configured")
+	ErrDuplicateDetector = errors.New("detector configured twice")
+	ErrInput             = errors.New("input is not a directory")
+)
+
+// StateName is the file in the output directory recording the files already processed
+const StateName = "watch-state.json"
+
+// statusDuplicate records a file left out because another input writes the same outputs
+const statusDuplicate = "duplicate"
+
+type Options struct {
+	Input    string            // directory watched, including its subdirectories
+	Output   string            // directory mirroring the input, with one tree per detector
+	Configs  []detector.Config // detectors run on every new image
+	Format   serialize.Format
+	Overlay  bool          // also render the corners over each image as a png
+	Workers  int           // images processed at once, 0 for one per cpu
+	Interval time.Duration // time between two scans of the input
+	Process  func(batch.Entry)
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Output:   "watch",
+		Configs:  []detector.Config{{Detector: "harris"}},
+		Format:   serialize.JSON,
+		Interval: 2 * time.Second,
+	}
+}
+
+// stat identifies a version of a file. A file replaced by another version is processed again.
+type stat struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+type fileState struct {
+	stat
+	Status string `json:"status"`
+}
+
+// state is what the state file holds. It only applies to a watcher with equal settings.
+type state struct {
+	Settings json.RawMessage      `json:"settings"`
+	Files    map[string]fileState `json:"files"`
+}
+
+type settings struct {
+	Configs []detector.Config `json:"configs"`
+	Format  serialize.Format  `json:"format"`
+	Overlay bool              `json:"overlay"`
+}
+
+// Watcher scans the input directory and processes the images that are new or changed since the
+// last scan. A file is only processed once its size and modification time held still between two
+// scans and it was last written at least an interval ago, so images still being copied in are left
+// alone. Failed images are recorded too and only retried once they change, as are images whose
+// outputs would overwrite those of another image with the same name and a different extension.
+type Watcher struct {
+	opts     Options
+	output   string // absolute output directory, skipped when it lies inside the input
+	settings []byte
+	state    state
+	pending  map[string]stat
+}
+
+// New checks the options and loads the state of an earlier watcher with the same settings
+func New(opts Options) (*Watcher, error) {
+	if len(opts.Configs) == 0 {
+		return nil, ErrNoDetectors
+	}
+	seen := map[string]bool{}
+	for _, cfg := range opts.Configs {
+		if seen[cfg.Detector] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateDetector, cfg.Detector)
+		}
+		seen[cfg.Detector] = true
+	}
+	if info, err := os.Stat(opts.Input); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s", ErrInput, opts.Input)
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.Format == "" {
+		opts.Format = serialize.JSON
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultOptions().Interval
+	}
+	if err := os.MkdirAll(opts.Output, 0o755); err != nil {
+		return nil, err
+	}
+	output, err := filepath.Abs(opts.Output)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(settings{Configs: opts.Configs, Format: opts.Format, Overlay: opts.Overlay})
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{opts: opts, output: output, settings: header, pending: map[string]stat{}}
+	w.state = w.load()
+	return w, nil
+}
+
+// load reads the state file, starting afresh when it is missing, unreadable or written with other
+// settings
+func (w *Watcher) load() state {
+	fresh := state{Settings: w.settings, Files: map[string]fileState{}}
+	data, err := os.ReadFile(filepath.Join(w.opts.Output, StateName))
+	if err != nil {
+		return fresh
+	}
+	var s state
+	if json.Unmarshal(data, &s) != nil || s.Files == nil {
+		return fresh
+	}
+	// the state file is indented, the settings are compared compact
+	var compact bytes.Buffer
+	if json.Compact(&compact, s.Settings) != nil || !bytes.Equal(compact.Bytes(), w.settings) {
+		return fresh
+	}
+	s.Settings = w.settings
+	return s
+}
+
+// save replaces the state file through a rename, so a crash never leaves it half written
+func (w *Watcher) save() error {
+	data, err := json.MarshalIndent(w.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(w.opts.Output, StateName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Processed returns the number of files recorded as processed, failed ones included
+func (w *Watcher) Processed() int {
+	return len(w.state.Files)
+}
+
+// Poll scans the input once and processes the images that are ready, returning how many. With
+// settle false every new image is ready at once, which suits a single pass over a folder that is
+// no longer written to.
+func (w *Watcher) Poll(ctx context.Context, settle bool) (int, error) {
+	var ready []string
+	stats := map[string]stat{}
+	present := map[string]bool{}
+	err := filepath.WalkDir(w.opts.Input, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// a file removed while scanning is simply gone
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && abs == w.output {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !batch.IsImage(path) || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(w.opts.Input, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		present[rel] = true
+		cur := stat{Size: info.Size(), ModTime: info.ModTime().UTC()}
+		if done, ok := w.state.Files[rel]; ok && done.stat.equal(cur) {
+			return nil
+		}
+		if settle {
+			if last, ok := w.pending[rel]; !ok || !last.equal(cur) || time.Since(cur.ModTime) < w.opts.Interval {
+				w.pending[rel] = cur
+				return nil
+			}
+		}
+		ready = append(ready, rel)
+		stats[rel] = cur
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for rel := range w.pending {
+		if !present[rel] || stats[rel] != (stat{}) {
+			delete(w.pending, rel)
+		}
+	}
+	if len(ready) == 0 {
+		return 0, ctx.Err()
+	}
+	sort.Strings(ready)
+
+	// images that differ only in their extension would write the same outputs. The one processed
+	// before, or else the first by name, keeps them and the others fail until they change.
+	owners := map[string]string{}
+	for rel := range present {
+		if owner, ok := owners[stem(rel)]; !ok || w.owns(rel, owner) {
+			owners[stem(rel)] = rel
+		}
+	}
+	n := 0
+	unique := ready[:0]
+	for _, rel := range ready {
+		owner := owners[stem(rel)]
+		if owner == rel {
+			unique = append(unique, rel)
+			continue
+		}
+		w.state.Files[rel] = fileState{stat: stats[rel], Status: statusDuplicate}
+		n++
+		if w.opts.Process != nil {
+			w.opts.Process(batch.Entry{
+				Input:  w.inputPath(rel),
+				Status: batch.StatusFailed,
+				Error:  fmt.Sprintf("%v: %s and %s", batch.ErrDuplicate, w.inputPath(owner), w.inputPath(rel)),
+			})
+		}
+	}
+	ready = unique
+
+	statuses := w.process(ctx, ready)
+	for _, rel := range ready {
+		status, ok := statuses[rel]
+		if !ok {
+			// not started before ctx was cancelled
+			continue
+		}
+		w.state.Files[rel] = fileState{stat: stats[rel], Status: status}
+		n++
+	}
+	if err := w.save(); err != nil {
+		return n, err
+	}
+	return n, ctx.Err()
+}
+
+// stem is the output path of an input relative to the tree of a detector, without extension
+func stem(rel string) string {
+	return strings.TrimSuffix(rel, filepath.Ext(rel))
+}
+
+// owns tells whether rel rather than other keeps the outputs they share
+func (w *Watcher) owns(rel, other string) bool {
+	processed := func(rel string) bool {
+		f, ok := w.state.Files[rel]
+		return ok && f.Status != statusDuplicate
+	}
+	if processed(rel) != processed(other) {
+		return processed(rel)
+	}
+	return rel < other
+}
+
+func (w *Watcher) inputPath(rel string) string {
+	return filepath.Join(w.opts.Input, filepath.FromSlash(rel))
+}
+
+func (a stat) equal(b stat) bool {
+	return a.Size == b.Size && a.ModTime.Equal(b.ModTime)
+}
+
+type job struct {
+	rel string
+	cfg detector.Config
+}
+
+type result struct {
+	rel   string
+	entry batch.Entry
+}
+
+// process runs every detector on the ready images with a pool of workers and returns the status of
+// every image that was processed by all of them, failed when any detector failed
+func (w *Watcher) process(ctx context.Context, ready []string) map[string]string {
+	jobs := make(chan job)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < w.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				in := batch.Input{Path: w.inputPath(j.rel), Rel: stem(j.rel)}
+				opts := batch.Options{
+					Output: filepath.Join(w.opts.Output, j.cfg.Detector), Config: j.cfg,
+					Format: w.opts.Format, Overlay: w.opts.Overlay,
+				}
+				results <- result{j.rel, batch.Process(in, opts)}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, rel := range ready {
+			for _, cfg := range w.opts.Configs {
+				select {
+				case jobs <- job{rel, cfg}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	done := map[string]int{}
+	statuses := map[string]string{}
+	for r := range results {
+		done[r.rel]++
+		if statuses[r.rel] != batch.StatusFailed {
+			statuses[r.rel] = r.entry.Status
+		}
+		if w.opts.Process != nil {
+			w.opts.Process(r.entry)
+		}
+	}
+	for rel, n := range done {
+		if n < len(w.opts.Configs) {
+			delete(statuses, rel)
+		}
+	}
+	return statuses
+}
+
+// Run polls the input every interval until ctx is cancelled, which it reports as a nil error. The
+// first scan happens at once.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Poll(ctx, true); err != nil && ctx.Err() == nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
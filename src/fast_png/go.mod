@@ -1,3 +0,0 @@
-module github.com/adityachopra29/fast_detection
-
-go 1.23.0
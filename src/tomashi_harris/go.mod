@@ -1,3 +0,0 @@
-module github.com/adityachopra29/tomashi_harris
-
-go 1.23.0